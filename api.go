@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
@@ -35,6 +36,15 @@ type (
 		Json    interface{}
 		Headers http.Header
 		Cookies *cookiejar.Jar
+		Context context.Context
+		// Stream leaves the response body unread. Read it from Response.Body and close it.
+		Stream bool
+		// UploadProgress and DownloadProgress are called while the request and
+		// the response body are transferred, every ProgressInterval (200ms by default)
+		// and once more when done.
+		UploadProgress   func(Progress)
+		DownloadProgress func(Progress)
+		ProgressInterval time.Duration
 		// files     string
 		Auth           *Auth
 		Timeout        *Timeout
@@ -42,6 +52,9 @@ type (
 		// proxies   string
 		// verify    string
 		// cert      SSLClientCert
+
+		// noSessionAuth sends the request without Session.Auth
+		noSessionAuth bool
 	}
 	Timeout struct {
 		Connect time.Duration
@@ -64,8 +77,11 @@ type Response struct {
 	contentLength int64
 	history       []http.Request
 	body          *bytes.Buffer
+	stream        io.ReadCloser
 	cookies       []*http.Cookie
 	headers       http.Header
+	elapsed       time.Duration
+	timings       []Timing
 }
 
 func redirectPolicyFunc(r *RequestParams) func(*http.Request, []*http.Request) error {
@@ -111,43 +127,95 @@ func setCookie(r *RequestParams) http.CookieJar {
 	return r.Cookies
 }
 
-func send(method, urlStr string, queryString *url.Values, r *RequestParams) (Response, error) {
+func stream(r *RequestParams) bool {
+	return r != nil && r.Stream
+}
+
+func requestContext(r *RequestParams) context.Context {
+	if r == nil || r.Context == nil {
+		return context.Background()
+	}
+	return r.Context
+}
+
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func (s *Session) send(method, urlStr string, queryString *url.Values, r *RequestParams) (Response, error) {
+
+	// each request gets its own copy of the http.Client, so that concurrent
+	// requests with different RequestParams do not interfere
+	hc := *s.client.client
+	c := &client{client: &hc, session: s}
 
 	// redirect
-	httpClient.client.CheckRedirect = redirectPolicyFunc(r)
+	hc.CheckRedirect = redirectPolicyFunc(r)
 
 	// cookie
-	httpClient.client.Jar = setCookie(r)
+	hc.Jar = setCookie(r)
+
+	// transport
+	hc.Transport = s.client.transport(s.Transport)
 
 	// timeout
 	readTimeout, connTimeout := timeout(r)
-	httpClient.client.Timeout = readTimeout
+	hc.Timeout = readTimeout
 	var (
 		ctx    context.Context
 		cancel context.CancelFunc
+		timer  *time.Timer
 	)
 	if connTimeout == 0 {
-		ctx, cancel = context.WithCancel(context.Background())
+		ctx, cancel = context.WithCancel(requestContext(r))
+	} else if stream(r) {
+		// a streamed body outlives the request, so the timeout ends with the response headers
+		ctx, cancel = context.WithCancel(requestContext(r))
+		timer = time.AfterFunc(connTimeout, cancel)
 	} else {
-		ctx, cancel = context.WithTimeout(context.Background(), connTimeout)
+		ctx, cancel = context.WithTimeout(requestContext(r), connTimeout)
 	}
-	defer cancel()
 
-	req, err := httpClient.newRequest(method, urlStr, queryString, r)
+	req, err := c.newRequest(method, urlStr, queryString, r)
 	if err != nil {
+		cancel()
 		return Response{}, err
 	}
 	req = req.WithContext(ctx)
 
-	resp, err := httpClient.do(req)
+	resp, err := c.do(req, r)
+	if timer != nil {
+		timer.Stop()
+	}
 	if err != nil {
+		cancel()
 		return Response{}, err
 	}
+	if resp.stream != nil {
+		resp.stream = &cancelCloser{ReadCloser: resp.stream, cancel: cancel}
+	} else {
+		cancel()
+	}
 
 	return resp, nil
 }
 
-func sendAsync(method, urlStr string, queryString *url.Values, r *RequestParams) (chan Response, chan error) {
+// cancelCloser releases the request context when a streamed body is closed
+type cancelCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelCloser) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+func (s *Session) sendAsync(method, urlStr string, queryString *url.Values, r *RequestParams) (chan Response, chan error) {
 	respCh := make(chan Response)
 	errCh := make(chan error)
 	go func() {
@@ -155,7 +223,7 @@ func sendAsync(method, urlStr string, queryString *url.Values, r *RequestParams)
 			close(respCh)
 			close(errCh)
 		}()
-		resp, err := send(method, urlStr, queryString, r)
+		resp, err := s.send(method, urlStr, queryString, r)
 		if err != nil {
 			errCh <- err
 			return
@@ -168,72 +236,72 @@ func sendAsync(method, urlStr string, queryString *url.Values, r *RequestParams)
 
 // Head makes HTTP(s) HEAD request with given urlStr, queryString and RequestParams
 func Head(urlStr string, queryString *url.Values, r *RequestParams) (Response, error) {
-	return send(http.MethodHead, urlStr, queryString, r)
+	return defaultSession.send(http.MethodHead, urlStr, queryString, r)
 }
 
 // HeadAsync makes asynchronous HTTP(s) HEAD request with given urlStr, queryString and RequestParams
 func HeadAsync(urlStr string, queryString *url.Values, r *RequestParams) (chan Response, chan error) {
-	return sendAsync(http.MethodHead, urlStr, queryString, r)
+	return defaultSession.sendAsync(http.MethodHead, urlStr, queryString, r)
 }
 
 // Get makes HTTP(s) GET request with given urlStr, queryString and RequestParams
 func Get(urlStr string, queryString *url.Values, r *RequestParams) (Response, error) {
-	return send(http.MethodGet, urlStr, queryString, r)
+	return defaultSession.send(http.MethodGet, urlStr, queryString, r)
 }
 
 // GetAsync makes asynchronous HTTP(s) GET request with given urlStr, queryString and RequestParams
 func GetAsync(urlStr string, queryString *url.Values, r *RequestParams) (chan Response, chan error) {
-	return sendAsync(http.MethodGet, urlStr, queryString, r)
+	return defaultSession.sendAsync(http.MethodGet, urlStr, queryString, r)
 }
 
 // Post makes HTTP(s) POST request with given urlStr, queryString and RequestParams
 func Post(urlStr string, queryString *url.Values, r *RequestParams) (Response, error) {
-	return send(http.MethodPost, urlStr, queryString, r)
+	return defaultSession.send(http.MethodPost, urlStr, queryString, r)
 }
 
 // PostAsync makes asynchronous HTTP(s) POST request with given urlStr, queryString and RequestParams
 func PostAsync(urlStr string, queryString *url.Values, r *RequestParams) (chan Response, chan error) {
-	return sendAsync(http.MethodPost, urlStr, queryString, r)
+	return defaultSession.sendAsync(http.MethodPost, urlStr, queryString, r)
 }
 
 // Put makes HTTP(s) PUT request with given urlStr, queryString and RequestParams
 func Put(urlStr string, queryString *url.Values, r *RequestParams) (Response, error) {
-	return send(http.MethodPut, urlStr, queryString, r)
+	return defaultSession.send(http.MethodPut, urlStr, queryString, r)
 }
 
 // PutAsync makes asynchronous HTTP(s) PUT request with given urlStr, queryString and RequestParams
 func PutAsync(urlStr string, queryString *url.Values, r *RequestParams) (chan Response, chan error) {
-	return sendAsync(http.MethodPut, urlStr, queryString, r)
+	return defaultSession.sendAsync(http.MethodPut, urlStr, queryString, r)
 }
 
 // Patch makes HTTP(s) PATCH request with given urlStr, queryString and RequestParams
 func Patch(urlStr string, queryString *url.Values, r *RequestParams) (Response, error) {
-	return send(http.MethodPatch, urlStr, queryString, r)
+	return defaultSession.send(http.MethodPatch, urlStr, queryString, r)
 }
 
 // PatchAsync makes asynchronous HTTP(s) PATCH request with given urlStr, queryString and RequestParams
 func PatchAsync(urlStr string, queryString *url.Values, r *RequestParams) (chan Response, chan error) {
-	return sendAsync(http.MethodPatch, urlStr, queryString, r)
+	return defaultSession.sendAsync(http.MethodPatch, urlStr, queryString, r)
 }
 
 // Delete makes HTTP(s) DELETE request with given urlStr, queryString and RequestParams
 func Delete(urlStr string, queryString *url.Values, r *RequestParams) (Response, error) {
-	return send(http.MethodDelete, urlStr, queryString, r)
+	return defaultSession.send(http.MethodDelete, urlStr, queryString, r)
 }
 
 // DeleteAsync makes asynchronous HTTP(s) DELETE request with given urlStr, queryString and RequestParams
 func DeleteAsync(urlStr string, queryString *url.Values, r *RequestParams) (chan Response, chan error) {
-	return sendAsync(http.MethodDelete, urlStr, queryString, r)
+	return defaultSession.sendAsync(http.MethodDelete, urlStr, queryString, r)
 }
 
 // Options makes HTTP(s) OPTIONS request with given urlStr, queryString and RequestParams
 func Options(urlStr string, queryString *url.Values, r *RequestParams) (Response, error) {
-	return send(http.MethodOptions, urlStr, queryString, r)
+	return defaultSession.send(http.MethodOptions, urlStr, queryString, r)
 }
 
 // OptionsAsync makes asynchronous HTTP(s) OPTIONS request with given urlStr, queryString and RequestParams
 func OptionsAsync(urlStr string, queryString *url.Values, r *RequestParams) (chan Response, chan error) {
-	return sendAsync(http.MethodOptions, urlStr, queryString, r)
+	return defaultSession.sendAsync(http.MethodOptions, urlStr, queryString, r)
 }
 
 // Url returns URL which you requested
@@ -260,6 +328,18 @@ func (resp Response) Content() []byte { return resp.body.Bytes() }
 // Raw returns HTTP response body in *bytes.Buffer
 func (resp Response) Raw() *bytes.Buffer { return resp.body }
 
+// Body returns HTTP response body in io.ReadCloser. If RequestParams.Stream is
+// set, it is read from the connection and Text, Content and Raw are empty.
+func (resp Response) Body() io.ReadCloser {
+	if resp.stream != nil {
+		return resp.stream
+	}
+	if resp.body == nil {
+		return io.NopCloser(&bytes.Buffer{})
+	}
+	return io.NopCloser(bytes.NewReader(resp.body.Bytes()))
+}
+
 // Json returns HTTP response body in Json
 func (resp Response) Json(dst interface{}) error {
 	return json.Unmarshal(resp.Content(), dst)
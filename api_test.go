@@ -640,3 +640,20 @@ func TestOptions(t *testing.T) {
 	assert.Equal(t, "GET, POST, PUT, DELETE, PATCH, OPTIONS",
 		resp.Headers().Get("Access-Control-Allow-Methods"), "")
 }
+
+func TestGetStream(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Stream Test"))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	resp, err := Get(ts.URL, nil, &RequestParams{Stream: true})
+	assert.Nil(t, err)
+	assert.Empty(t, resp.Text(), "Streamed body should not be buffered")
+	body := resp.Body()
+	defer body.Close()
+	b, err := io.ReadAll(body)
+	assert.Nil(t, err)
+	assert.Equal(t, "Stream Test", string(b))
+}
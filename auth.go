@@ -0,0 +1,53 @@
+package requests
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// Authenticator adds credentials to a request before it is sent, for example
+// an Authorization header or a signature. It is called again for every
+// redirect to the same host.
+//
+// An Authenticator may also have a method
+//
+//	Unauthorized(req *http.Request, resp *http.Response) bool
+//
+// which is called when the server answers 401. If it returns true, the
+// request is authenticated and sent once more.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// Authenticate implements Authenticator with HTTP Basic authentication
+func (a *Auth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// requestBody returns the body of req for signing. It is read from GetBody when
+// possible, otherwise the body is read and replaced with a copy.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+	b, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+	return b, nil
+}
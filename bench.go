@@ -0,0 +1,277 @@
+package requests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultBenchConcurrency = 10
+
+// BenchOptions configures Bench. It runs until Duration has passed or
+// Requests were sent, whichever comes first.
+type BenchOptions struct {
+	Duration time.Duration
+	Requests int
+	// Concurrency is the number of workers sending requests, 10 by default.
+	Concurrency int
+	// Rate is the number of requests per second to start. Without it every
+	// worker sends its next request as soon as the previous one is done.
+	//
+	// With a Rate, latency is measured from when a request was due, so the
+	// time spent waiting for a free worker of an overloaded server is counted
+	// rather than hidden.
+	Rate float64
+}
+
+// BenchResult is the outcome of Bench
+type BenchResult struct {
+	Duration time.Duration
+	// Requests is the number of requests sent, including failed ones.
+	Requests int
+	// Throughput is the number of responses per second.
+	Throughput float64
+	// Latency of the requests which got a response.
+	Latency     *Histogram
+	StatusCodes map[int]int
+	// Errors counts failed requests by error message.
+	Errors map[string]int
+}
+
+// Bench load tests urlStr with the default Session
+func Bench(method, urlStr string, queryString *url.Values, r *RequestParams, b *BenchOptions) (*BenchResult, error) {
+	return defaultSession.Bench(method, urlStr, queryString, r, b)
+}
+
+// Bench sends the request for method, urlStr, queryString and RequestParams
+// over and over, at a fixed concurrency or a fixed rate, and reports latency,
+// throughput and status codes. The bench stops early when r.Context is done.
+func (s *Session) Bench(method, urlStr string, queryString *url.Values, r *RequestParams, b *BenchOptions) (*BenchResult, error) {
+	if b == nil || (b.Duration <= 0 && b.Requests <= 0) {
+		return nil, errors.New("go-requests: bench needs a Duration or a number of Requests")
+	}
+	if b.Rate < 0 {
+		return nil, errors.New("go-requests: bench Rate must not be negative")
+	}
+	concurrency := b.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBenchConcurrency
+	}
+
+	// every request gets its own copy of the body
+	var template RequestParams
+	if r != nil {
+		template = *r
+	}
+	var data []byte
+	if template.Data != nil {
+		data = append([]byte(nil), template.Data.Bytes()...)
+	}
+	ctx := requestContext(r)
+
+	result := &BenchResult{
+		Latency:     NewHistogram(),
+		StatusCodes: map[int]int{},
+		Errors:      map[string]int{},
+	}
+	var mu sync.Mutex
+	var sent atomic.Int64
+
+	start := time.Now()
+	var deadline time.Time
+	if b.Duration > 0 {
+		deadline = start.Add(b.Duration)
+	}
+	// next reports whether another request is to be sent
+	next := func(at time.Time) bool {
+		if ctx.Err() != nil || (!deadline.IsZero() && !at.Before(deadline)) {
+			return false
+		}
+		return b.Requests <= 0 || sent.Add(1) <= int64(b.Requests)
+	}
+	overdue := func() bool {
+		return !deadline.IsZero() && !time.Now().Before(deadline)
+	}
+
+	send := func(due time.Time) {
+		p := template
+		p.Context = ctx
+		if data != nil {
+			p.Data = bytes.NewBuffer(data)
+		}
+		resp, err := s.send(method, urlStr, queryString, &p)
+		latency := time.Since(due)
+
+		if err != nil && ctx.Err() != nil {
+			// requests cut short by canceling the bench are not failures
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		result.Requests++
+		if err != nil {
+			result.Errors[err.Error()]++
+			return
+		}
+		result.Latency.Record(latency)
+		result.StatusCodes[resp.StatusCode()]++
+	}
+
+	var wg sync.WaitGroup
+	if b.Rate == 0 {
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					now := time.Now()
+					if !next(now) {
+						return
+					}
+					send(now)
+				}
+			}()
+		}
+	} else {
+		due := make(chan time.Time, concurrency)
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for t := range due {
+					// requests falling behind the rate are dropped after Duration
+					if !overdue() {
+						send(t)
+					}
+				}
+			}()
+		}
+		interval := time.Duration(float64(time.Second) / b.Rate)
+		for i := 0; ; i++ {
+			at := start.Add(time.Duration(i) * interval)
+			if !next(at) || !sleepUntil(ctx, at) || overdue() {
+				break
+			}
+			due <- at
+		}
+		close(due)
+	}
+	wg.Wait()
+
+	result.Duration = time.Since(start)
+	result.Throughput = float64(result.Latency.Count()) / result.Duration.Seconds()
+	return result, nil
+}
+
+// sleepUntil reports false if ctx is done first
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	d := time.Until(t)
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// benchQuantiles are the quantiles reported by WriteText and MarshalJSON
+var benchQuantiles = []struct {
+	name string
+	q    float64
+}{{"p50", 0.5}, {"p90", 0.9}, {"p99", 0.99}}
+
+// WriteText writes a human readable report
+func (r *BenchResult) WriteText(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Duration:     %s\n", r.Duration.Round(time.Millisecond))
+	fmt.Fprintf(&sb, "Requests:     %d\n", r.Requests)
+	fmt.Fprintf(&sb, "Throughput:   %.2f req/s\n", r.Throughput)
+	fmt.Fprintf(&sb, "Latency:      min %s, mean %s", roundDuration(r.Latency.Min()), roundDuration(r.Latency.Mean()))
+	for _, q := range benchQuantiles {
+		fmt.Fprintf(&sb, ", %s %s", q.name, roundDuration(r.Latency.Quantile(q.q)))
+	}
+	fmt.Fprintf(&sb, ", max %s\n", roundDuration(r.Latency.Max()))
+
+	codes := make([]int, 0, len(r.StatusCodes))
+	for code := range r.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	sb.WriteString("Status codes:")
+	for i, code := range codes {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, " %d: %d", code, r.StatusCodes[code])
+	}
+	sb.WriteString("\n")
+
+	if len(r.Errors) > 0 {
+		errs := make([]string, 0, len(r.Errors))
+		for e := range r.Errors {
+			errs = append(errs, e)
+		}
+		sort.Slice(errs, func(i, j int) bool {
+			if r.Errors[errs[i]] != r.Errors[errs[j]] {
+				return r.Errors[errs[i]] > r.Errors[errs[j]]
+			}
+			return errs[i] < errs[j]
+		})
+		sb.WriteString("Errors:\n")
+		for _, e := range errs {
+			fmt.Fprintf(&sb, "  %d  %s\n", r.Errors[e], e)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// roundDuration keeps three significant digits
+func roundDuration(d time.Duration) time.Duration {
+	for unit := time.Duration(1); unit < time.Hour; unit *= 10 {
+		if d < 1000*unit {
+			return d.Round(unit)
+		}
+	}
+	return d.Round(time.Second)
+}
+
+// MarshalJSON reports durations in milliseconds
+func (r *BenchResult) MarshalJSON() ([]byte, error) {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	latency := map[string]float64{
+		"min":  ms(r.Latency.Min()),
+		"mean": ms(r.Latency.Mean()),
+		"max":  ms(r.Latency.Max()),
+	}
+	for _, q := range benchQuantiles {
+		latency[q.name] = ms(r.Latency.Quantile(q.q))
+	}
+	codes := make(map[string]int, len(r.StatusCodes))
+	for code, n := range r.StatusCodes {
+		codes[strconv.Itoa(code)] = n
+	}
+	return json.Marshal(struct {
+		DurationMs  float64            `json:"duration_ms"`
+		Requests    int                `json:"requests"`
+		Throughput  float64            `json:"throughput"`
+		LatencyMs   map[string]float64 `json:"latency_ms"`
+		StatusCodes map[string]int     `json:"status_codes"`
+		Errors      map[string]int     `json:"errors"`
+	}{ms(r.Duration), r.Requests, r.Throughput, latency, codes, r.Errors})
+}
@@ -0,0 +1,132 @@
+package requests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBenchConcurrency(t *testing.T) {
+	var n atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "payload" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if n.Add(1)%10 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	s := NewSession()
+	res, err := s.Bench(http.MethodPost, ts.URL, nil, &RequestParams{Data: bytes.NewBufferString("payload")}, &BenchOptions{Requests: 100, Concurrency: 4})
+	assert.NoError(t, err)
+	assert.Equal(t, 100, res.Requests)
+	assert.Equal(t, map[int]int{200: 90, 503: 10}, res.StatusCodes)
+	assert.Empty(t, res.Errors)
+	assert.Equal(t, int64(100), res.Latency.Count())
+	assert.Greater(t, res.Throughput, 0.0)
+
+	_, err = s.Bench(http.MethodGet, ts.URL, nil, nil, &BenchOptions{})
+	assert.Error(t, err)
+}
+
+func TestBenchRate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	res, err := Bench(http.MethodGet, ts.URL, nil, nil, &BenchOptions{Duration: 500 * time.Millisecond, Rate: 40})
+	assert.NoError(t, err)
+	// requests are due at 0, 25ms, ... 475ms
+	assert.Equal(t, 20, res.Requests)
+	assert.Equal(t, map[int]int{200: 20}, res.StatusCodes)
+	assert.GreaterOrEqual(t, res.Duration, 475*time.Millisecond)
+}
+
+func TestBenchRateDeadline(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	// the requests falling behind the rate are not sent after Duration
+	res, err := Bench(http.MethodGet, ts.URL, nil, nil, &BenchOptions{Duration: 200 * time.Millisecond, Rate: 1000, Concurrency: 1})
+	assert.NoError(t, err)
+	assert.Less(t, res.Duration, time.Second)
+	assert.LessOrEqual(t, res.Requests, 5)
+}
+
+func TestBenchErrorsAndCancel(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := ts.URL
+	ts.Close()
+
+	res, err := Bench(http.MethodGet, url, nil, nil, &BenchOptions{Requests: 5, Concurrency: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, res.Requests)
+	assert.Len(t, res.Errors, 1)
+	for _, n := range res.Errors {
+		assert.Equal(t, 5, n)
+	}
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer slow.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	res, err = Bench(http.MethodGet, slow.URL, nil, &RequestParams{Context: ctx}, &BenchOptions{Duration: time.Minute})
+	assert.NoError(t, err)
+	assert.Less(t, res.Duration, time.Second)
+	assert.Equal(t, 0, res.Requests)
+	assert.Empty(t, res.Errors)
+}
+
+func TestBenchReport(t *testing.T) {
+	h := NewHistogram()
+	for i := 1; i <= 100; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+	res := &BenchResult{
+		Duration:    2 * time.Second,
+		Requests:    102,
+		Throughput:  50,
+		Latency:     h,
+		StatusCodes: map[int]int{200: 98, 500: 2},
+		Errors:      map[string]int{"connection refused": 2},
+	}
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, res.WriteText(buf))
+	assert.Equal(t, strings.Join([]string{
+		"Duration:     2s",
+		"Requests:     102",
+		"Throughput:   50.00 req/s",
+		"Latency:      min 1ms, mean 50.5ms, p50 50.1ms, p90 90.2ms, p99 99.1ms, max 100ms",
+		"Status codes: 200: 98, 500: 2",
+		"Errors:",
+		"  2  connection refused",
+		"",
+	}, "\n"), buf.String())
+
+	b, err := json.Marshal(res)
+	assert.NoError(t, err)
+	var got map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, 2000.0, got["duration_ms"])
+	assert.Equal(t, map[string]interface{}{"200": 98.0, "500": 2.0}, got["status_codes"])
+	latency := got["latency_ms"].(map[string]interface{})
+	assert.Equal(t, 100.0, latency["max"])
+	assert.InEpsilon(t, 99.0, latency["p99"], 0.01)
+}
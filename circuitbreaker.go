@@ -0,0 +1,245 @@
+package requests
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is matched by errors.Is for every CircuitOpenError
+var ErrCircuitOpen = errors.New("go-requests: circuit open")
+
+// CircuitOpenError is returned when a request is refused because the circuit of Host is open
+type CircuitOpenError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return "go-requests: circuit open for " + e.Host + " until " + e.Until.Format(time.RFC3339)
+}
+
+func (e *CircuitOpenError) Is(target error) bool { return target == ErrCircuitOpen }
+
+// CircuitState is the state of a circuit
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+const (
+	defaultFailureRatio     = 0.5
+	defaultMinRequests      = 10
+	defaultCircuitWindow    = 60 * time.Second
+	defaultCircuitCoolDown  = 30 * time.Second
+	defaultHalfOpenRequests = 1
+)
+
+// CircuitBreaker stops sending requests to a host once FailureRatio of the
+// requests within Window have failed. After CoolDown, HalfOpenRequests trial
+// requests are let through; a success closes the circuit, a failure opens it again.
+// Zero values use the defaults above.
+type CircuitBreaker struct {
+	FailureRatio     float64
+	MinRequests      int
+	Window           time.Duration
+	CoolDown         time.Duration
+	HalfOpenRequests int
+	// IsFailure reports whether a request failed. By default errors and 5xx responses are failures.
+	IsFailure func(resp *http.Response, err error) bool
+	// OnStateChange is called whenever the circuit of host changes its state.
+	OnStateChange func(host string, from, to CircuitState)
+
+	mu    sync.Mutex
+	hosts map[string]*circuit
+}
+
+type outcome struct {
+	at     time.Time
+	failed bool
+}
+
+type circuit struct {
+	state    CircuitState
+	outcomes []outcome
+	openedAt time.Time
+	trials   int
+}
+
+// State returns the current state of the circuit for host
+func (cb *CircuitBreaker) State(host string) CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	c, ok := cb.hosts[host]
+	if !ok {
+		return CircuitClosed
+	}
+	if c.state == CircuitOpen && time.Since(c.openedAt) >= cb.coolDown() {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+func (cb *CircuitBreaker) circuit(host string) *circuit {
+	if cb.hosts == nil {
+		cb.hosts = make(map[string]*circuit)
+	}
+	c, ok := cb.hosts[host]
+	if !ok {
+		c = &circuit{}
+		cb.hosts[host] = c
+	}
+	return c
+}
+
+func (cb *CircuitBreaker) allow(host string) error {
+	cb.mu.Lock()
+	c := cb.circuit(host)
+	from := c.state
+	if c.state == CircuitOpen {
+		if until := c.openedAt.Add(cb.coolDown()); time.Now().Before(until) {
+			cb.mu.Unlock()
+			return &CircuitOpenError{Host: host, Until: until}
+		}
+		c.state, c.trials = CircuitHalfOpen, 0
+	}
+	if c.state == CircuitHalfOpen {
+		if c.trials >= cb.halfOpenRequests() {
+			until := c.openedAt.Add(cb.coolDown())
+			cb.mu.Unlock()
+			return &CircuitOpenError{Host: host, Until: until}
+		}
+		c.trials++
+	}
+	to := c.state
+	cb.mu.Unlock()
+
+	cb.changed(host, from, to)
+	return nil
+}
+
+// release gives back the trial of allow for a request that was not sent
+func (cb *CircuitBreaker) release(host string) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if c, ok := cb.hosts[host]; ok && c.state == CircuitHalfOpen && c.trials > 0 {
+		c.trials--
+	}
+}
+
+func (cb *CircuitBreaker) record(host string, resp *http.Response, err error) {
+	if resp != nil {
+		// the previous response of a redirect is returned with an error
+		err = nil
+	}
+	failed := cb.isFailure(resp, err)
+	now := time.Now()
+
+	cb.mu.Lock()
+	c := cb.circuit(host)
+	from := c.state
+	switch c.state {
+	case CircuitHalfOpen:
+		if failed {
+			c.state, c.openedAt = CircuitOpen, now
+		} else {
+			c.state = CircuitClosed
+		}
+		c.outcomes = nil
+	case CircuitClosed:
+		c.outcomes = append(c.outcomes, outcome{at: now, failed: failed})
+		c.prune(now.Add(-cb.window()))
+		if cb.tripped(c) {
+			c.state, c.openedAt, c.outcomes = CircuitOpen, now, nil
+		}
+	}
+	to := c.state
+	cb.mu.Unlock()
+
+	cb.changed(host, from, to)
+}
+
+func (c *circuit) prune(since time.Time) {
+	i := 0
+	for i < len(c.outcomes) && c.outcomes[i].at.Before(since) {
+		i++
+	}
+	c.outcomes = c.outcomes[i:]
+}
+
+func (cb *CircuitBreaker) tripped(c *circuit) bool {
+	if len(c.outcomes) < cb.minRequests() {
+		return false
+	}
+	failures := 0
+	for _, o := range c.outcomes {
+		if o.failed {
+			failures++
+		}
+	}
+	return float64(failures)/float64(len(c.outcomes)) >= cb.failureRatio()
+}
+
+func (cb *CircuitBreaker) changed(host string, from, to CircuitState) {
+	if from != to && cb.OnStateChange != nil {
+		cb.OnStateChange(host, from, to)
+	}
+}
+
+func (cb *CircuitBreaker) isFailure(resp *http.Response, err error) bool {
+	if cb.IsFailure != nil {
+		return cb.IsFailure(resp, err)
+	}
+	return err != nil || resp.StatusCode >= 500
+}
+
+func (cb *CircuitBreaker) failureRatio() float64 {
+	if cb.FailureRatio <= 0 {
+		return defaultFailureRatio
+	}
+	return cb.FailureRatio
+}
+
+func (cb *CircuitBreaker) minRequests() int {
+	if cb.MinRequests <= 0 {
+		return defaultMinRequests
+	}
+	return cb.MinRequests
+}
+
+func (cb *CircuitBreaker) window() time.Duration {
+	if cb.Window <= 0 {
+		return defaultCircuitWindow
+	}
+	return cb.Window
+}
+
+func (cb *CircuitBreaker) coolDown() time.Duration {
+	if cb.CoolDown <= 0 {
+		return defaultCircuitCoolDown
+	}
+	return cb.CoolDown
+}
+
+func (cb *CircuitBreaker) halfOpenRequests() int {
+	if cb.HalfOpenRequests <= 0 {
+		return defaultHalfOpenRequests
+	}
+	return cb.HalfOpenRequests
+}
@@ -0,0 +1,161 @@
+package requests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var healthy int32
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&healthy) == 1 {
+			w.WriteHeader(200)
+			return
+		}
+		w.WriteHeader(503)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	var changes []string
+	s := NewSession()
+	s.CircuitBreaker = &CircuitBreaker{
+		FailureRatio: 0.5,
+		MinRequests:  2,
+		CoolDown:     100 * time.Millisecond,
+		OnStateChange: func(host string, from, to CircuitState) {
+			changes = append(changes, from.String()+">"+to.String())
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := s.Get(ts.URL, nil, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 503, resp.StatusCode())
+	}
+	host := ts.URL[len("http://"):]
+	assert.Equal(t, CircuitOpen, s.CircuitBreaker.State(host))
+
+	_, err := s.Get(ts.URL, nil, nil)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	openErr, ok := err.(*CircuitOpenError)
+	assert.True(t, ok)
+	assert.Equal(t, host, openErr.Host)
+
+	time.Sleep(150 * time.Millisecond)
+	atomic.StoreInt32(&healthy, 1)
+	resp, err := s.Get(ts.URL, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode())
+	assert.Equal(t, CircuitClosed, s.CircuitBreaker.State(host))
+	assert.Equal(t, []string{"closed>open", "open>half-open", "half-open>closed"}, changes)
+}
+
+func TestCircuitBreakerHalfOpenFailure(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	s := NewSession()
+	s.CircuitBreaker = &CircuitBreaker{
+		MinRequests: 1,
+		CoolDown:    50 * time.Millisecond,
+	}
+	host := ts.URL[len("http://"):]
+
+	s.Get(ts.URL, nil, nil)
+	assert.Equal(t, CircuitOpen, s.CircuitBreaker.State(host))
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, CircuitHalfOpen, s.CircuitBreaker.State(host))
+	_, err := s.Get(ts.URL, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, CircuitOpen, s.CircuitBreaker.State(host))
+}
+
+// failingAuth fails to authenticate every request
+type failingAuth struct{}
+
+func (failingAuth) Authenticate(req *http.Request) error { return errors.New("no credentials") }
+
+func TestCircuitBreakerHalfOpenNotSent(t *testing.T) {
+	var status int32 = 500
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s := atomic.LoadInt32(&status); s != 401 {
+			w.WriteHeader(int(s))
+			return
+		}
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(401)
+		}
+	}))
+	defer ts.Close()
+
+	s := NewSession()
+	s.CircuitBreaker = &CircuitBreaker{
+		MinRequests: 1,
+		CoolDown:    50 * time.Millisecond,
+	}
+	host := ts.URL[len("http://"):]
+	s.Get(ts.URL, nil, nil)
+	assert.Equal(t, CircuitOpen, s.CircuitBreaker.State(host))
+	time.Sleep(60 * time.Millisecond)
+
+	// a request failing before it is sent gives its trial back
+	s.Auth = failingAuth{}
+	_, err := s.Get(ts.URL, nil, nil)
+	assert.EqualError(t, err, "no credentials")
+	assert.Equal(t, CircuitHalfOpen, s.CircuitBreaker.State(host))
+
+	// the retry after 401 Unauthorized is no trial of its own
+	atomic.StoreInt32(&status, 401)
+	s.Auth = &retryingAuth{}
+	resp, err := s.Get(ts.URL, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode())
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, CircuitClosed, s.CircuitBreaker.State(host))
+}
+
+func TestCircuitBreakerRetryRateLimited(t *testing.T) {
+	var status int32 = 500
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer ts.Close()
+
+	s := NewSession()
+	s.CircuitBreaker = &CircuitBreaker{
+		MinRequests: 1,
+		CoolDown:    50 * time.Millisecond,
+	}
+	host := ts.URL[len("http://"):]
+	s.Get(ts.URL, nil, nil)
+	assert.Equal(t, CircuitOpen, s.CircuitBreaker.State(host))
+	time.Sleep(60 * time.Millisecond)
+
+	// the retry after 401 Unauthorized is refused by the rate limiter
+	atomic.StoreInt32(&status, 401)
+	s.Auth = &retryingAuth{}
+	s.RateLimiter = &RateLimiter{Default: &RateLimit{Rate: 0.1, Burst: 1}, FailFast: true}
+	_, err := s.Get(ts.URL, nil, nil)
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, CircuitHalfOpen, s.CircuitBreaker.State(host))
+
+	atomic.StoreInt32(&status, 200)
+	s.RateLimiter = nil
+	resp, err := s.Get(ts.URL, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode())
+	assert.Equal(t, CircuitClosed, s.CircuitBreaker.State(host))
+}
@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -15,14 +17,19 @@ const (
 )
 
 type client struct {
-	client *http.Client
-}
+	client  *http.Client
+	session *Session
 
-var httpClient = newClient()
+	mu            sync.Mutex
+	transportFrom *Transport
+	roundTripper  http.RoundTripper
+	built         bool
+}
 
-func newClient() *client {
+func newClient(s *Session) *client {
 	return &client{
-		client: &http.Client{},
+		client:  &http.Client{},
+		session: s,
 	}
 }
 
@@ -50,7 +57,7 @@ func (c *client) requestData(r *RequestParams) (io.ReadWriter, error) {
 
 func (c *client) newRequest(method, urlStr string, queryString *url.Values, r *RequestParams) (*http.Request, error) {
 
-	u, err := url.Parse(urlStr)
+	u, err := parseURL(urlStr)
 	if err != nil {
 		return nil, err
 	}
@@ -68,9 +75,25 @@ func (c *client) newRequest(method, urlStr string, queryString *url.Values, r *R
 		return nil, err
 	}
 
+	if r != nil && r.UploadProgress != nil && req.Body != nil && req.Body != http.NoBody {
+		total := req.ContentLength
+		if total == 0 {
+			total = -1
+		}
+		req.Body = &progressReader{
+			ReadCloser: req.Body,
+			p:          newProgress(r.UploadProgress, r.ProgressInterval, total),
+		}
+	}
+
+	if _, ok := unixSocket(u.Host); ok {
+		req.Host = unixHostHeader
+	}
+
 	if r != nil {
 		if r.Headers != nil {
-			req.Header = r.Headers
+			// copied, as authenticators add headers to each request
+			req.Header = r.Headers.Clone()
 		}
 		if r.Auth != nil {
 			req.SetBasicAuth(r.Auth.Username, r.Auth.Password)
@@ -83,48 +106,184 @@ func (c *client) newRequest(method, urlStr string, queryString *url.Values, r *R
 	return req, nil
 }
 
-func (c *client) do(req *http.Request) (Response, error) {
+// rewindBody resets the body of req to send it again
+func rewindBody(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return true
+	}
+	if req.GetBody == nil {
+		return false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+	req.Body = body
+	return true
+}
+
+func (c *client) do(req *http.Request, r *RequestParams) (Response, error) {
 	var (
 		resp    *http.Response
 		history []http.Request
 		cookies []*http.Cookie
 		err     error
+		retried bool
+		allowed string // host allowed by the CircuitBreaker, until the request is recorded
+		hop     *hopTrace
+		timings []Timing
 	)
+	start := time.Now()
+
+	// credentials of the Session or .netrc are only sent to the host of the request,
+	// not to other hosts it redirects to
+	auth := c.session.Auth
+	if r != nil && r.noSessionAuth {
+		auth = nil
+	}
+	if r != nil && r.Auth != nil {
+		auth = nil
+	} else if auth == nil && !c.session.DisableNetrc && req.Header.Get("Authorization") == "" {
+		if _, ok := unixSocket(req.URL.Host); !ok {
+			if a := netrcAuth(req.URL.Hostname()); a != nil {
+				auth = a
+			}
+		}
+	}
+	origin := req.URL.Host
+	trace := newRequestTrace(req, c.session.Tracer)
+	var span *Span
+	metrics := c.session.Metrics
+	cb := c.session.CircuitBreaker
+	// a trial of the CircuitBreaker is given back by requests which end before
+	// they are recorded
+	defer func() {
+		if allowed != "" {
+			cb.release(allowed)
+		}
+	}()
+	// endHop finishes the timing, span and metrics of the request just sent
+	endHop := func(resp *http.Response, err error, bodyRead bool, received int64) {
+		t := hop.done(bodyRead)
+		if err == nil {
+			timings = append(timings, t)
+		}
+		trace.end(req, span, resp, err)
+		if metrics != nil {
+			metrics.observe(req, resp, err, t.Total, received)
+		}
+	}
 
 	for x := 0; x < maxRedirectCounts; x++ {
-		resp, err = c.client.Do(req)
+		if l := c.session.RateLimiter; l != nil {
+			if err = l.wait(req.Context(), req.URL.Host); err != nil {
+				return Response{}, err
+			}
+		}
+		// the retry after 401 Unauthorized is sent as part of the same request
+		if cb != nil && allowed == "" {
+			if err = cb.allow(req.URL.Host); err != nil {
+				return Response{}, err
+			}
+			allowed = req.URL.Host
+		}
+		// the trace headers are set before they may be signed
+		span = trace.start(req, len(timings))
+		if auth != nil && req.URL.Host == origin {
+			if err = auth.Authenticate(req); err != nil {
+				trace.end(req, span, nil, err)
+				return Response{}, err
+			}
+		} else if auth != nil {
+			req.Header.Del("Authorization")
+		}
+		if c.session.CurlLog != nil {
+			if cmd, err := c.session.curl(req, r, c.session.CurlOptions); err == nil {
+				c.session.CurlLog(cmd)
+			}
+		}
+		hop = newHopTrace(req.URL)
+		if metrics != nil {
+			metrics.start(req)
+		}
+		resp, err = c.client.Do(hop.request(req))
+		if err == nil && resp.StatusCode == http.StatusUnauthorized && !retried && req.URL.Host == origin {
+			if ra, ok := auth.(interface {
+				Unauthorized(*http.Request, *http.Response) bool
+			}); ok && ra.Unauthorized(req, resp) && rewindBody(req) {
+				resp.Body.Close()
+				endHop(resp, nil, false, 0)
+				if metrics != nil {
+					metrics.retry(req)
+				}
+				retried = true
+				x--
+				continue
+			}
+		}
+		if resp != nil && c.session.RateLimiter != nil {
+			c.session.RateLimiter.update(req.URL.Host, resp)
+		}
+		if cb != nil {
+			cb.record(req.URL.Host, resp, err)
+			allowed = ""
+		}
 		if err != nil {
 			if strings.Contains(err.Error(), "go-requests handles redirect") {
 				loc := resp.Header.Get("Location")
 				u, err := req.URL.Parse(loc)
 				if err != nil {
+					resp.Body.Close()
+					endHop(resp, err, false, 0)
 					return Response{}, err
 				}
+				endHop(resp, nil, false, 0)
 				history = append(history, *req)
 				req.URL = u
 				cookies = append(cookies, resp.Cookies()...)
 				defer resp.Body.Close()
 				continue
 			} else {
+				endHop(nil, err, false, 0)
 				return Response{}, err
 			}
 		}
 		cookies = append(cookies, resp.Cookies()...)
-		defer resp.Body.Close()
 		break
 	}
 
+	if r != nil && r.DownloadProgress != nil {
+		resp.Body = keepWriter(&progressReader{
+			ReadCloser: resp.Body,
+			p:          newProgress(r.DownloadProgress, r.ProgressInterval, resp.ContentLength),
+		}, resp.Body)
+	}
+
 	buf := &bytes.Buffer{}
-	io.Copy(buf, resp.Body)
+	var body io.ReadCloser
+	var received int64
+	if stream(r) {
+		body = resp.Body
+		if metrics != nil {
+			body = keepWriter(&meteredBody{ReadCloser: body, metrics: metrics, req: req}, body)
+		}
+	} else {
+		received, _ = io.Copy(buf, resp.Body)
+		resp.Body.Close()
+	}
+	endHop(resp, nil, !stream(r), received)
 	response := Response{
-		_url:          req.URL,
+		_url:          displayURL(req.URL),
 		headers:       resp.Header,
 		status:        resp.Status,
 		statusCode:    resp.StatusCode,
 		contentLength: resp.ContentLength,
 		history:       history,
 		body:          buf,
+		stream:        body,
 		cookies:       cookies,
+		elapsed:       time.Since(start),
+		timings:       timings,
 	}
 	return response, nil
 }
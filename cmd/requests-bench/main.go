@@ -0,0 +1,140 @@
+// Command requests-bench load tests a URL with go-requests and reports
+// latency percentiles, throughput, status codes and errors.
+//
+//	requests-bench [flags] URL
+//
+// By default 10 workers send requests back to back for 10s. With -rate the
+// requests are started at a fixed rate instead.
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	requests "github.com/hiroakis/go-requests"
+)
+
+// headers collects repeated -H flags
+type headers http.Header
+
+func (h headers) String() string { return "" }
+
+func (h headers) Set(v string) error {
+	name, value, ok := strings.Cut(v, ":")
+	if !ok {
+		return fmt.Errorf("invalid header %q, want Name: value", v)
+	}
+	http.Header(h).Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	return nil
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("requests-bench", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: requests-bench [flags] URL")
+		fs.PrintDefaults()
+	}
+	h := headers{}
+	var (
+		method      = fs.String("X", http.MethodGet, "request method")
+		data        = fs.String("d", "", "request body, or @file to read it from a file")
+		duration    = fs.Duration("duration", 10*time.Second, "how long to run")
+		n           = fs.Int("n", 0, "stop after this many requests")
+		concurrency = fs.Int("c", 10, "number of workers")
+		rate        = fs.Float64("rate", 0, "requests per second, 0 for as fast as the workers go")
+		timeout     = fs.Duration("timeout", 30*time.Second, "timeout of each request")
+		asJSON      = fs.Bool("json", false, "print the report as JSON")
+		insecure    = fs.Bool("k", false, "skip TLS certificate verification")
+	)
+	fs.Var(h, "H", "request header `Name: value`, can be repeated")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	// -n alone runs until the requests are sent
+	if *n > 0 && !flagSet(fs, "duration") {
+		*duration = 0
+	}
+
+	params := &requests.RequestParams{
+		Headers: http.Header(h),
+		Context: ctx,
+		Timeout: &requests.Timeout{Read: *timeout},
+	}
+	if *data != "" {
+		body := []byte(*data)
+		if strings.HasPrefix(*data, "@") {
+			var err error
+			if body, err = os.ReadFile((*data)[1:]); err != nil {
+				fmt.Fprintln(stderr, "requests-bench:", err)
+				return 1
+			}
+		}
+		params.Data = bytes.NewBuffer(body)
+	}
+
+	s := requests.NewSession()
+	defer s.Close()
+	// keep an idle connection for every worker, so that latencies do not
+	// include dialing again
+	s.Transport = &requests.Transport{MaxIdleConns: *concurrency, MaxIdleConnsPerHost: *concurrency}
+	if *insecure {
+		s.Transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	res, err := s.Bench(strings.ToUpper(*method), fs.Arg(0), nil, params, &requests.BenchOptions{
+		Duration:    *duration,
+		Requests:    *n,
+		Concurrency: *concurrency,
+		Rate:        *rate,
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, "requests-bench:", err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(res)
+	} else {
+		err = res.WriteText(stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "requests-bench:", err)
+		return 1
+	}
+	return 0
+}
+
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.Header.Get("X-Token") != "abc" || string(body) != `{"a":1}` {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	file := filepath.Join(t.TempDir(), "body.json")
+	os.WriteFile(file, []byte(`{"a":1}`), 0o644)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(context.Background(), []string{"-X", "post", "-H", "X-Token: abc", "-d", "@" + file, "-n", "20", "-c", "2", "-json", ts.URL}, stdout, stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	var report struct {
+		Requests    int                `json:"requests"`
+		StatusCodes map[string]int     `json:"status_codes"`
+		LatencyMs   map[string]float64 `json:"latency_ms"`
+	}
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	assert.Equal(t, 20, report.Requests)
+	assert.Equal(t, map[string]int{"200": 20}, report.StatusCodes)
+	assert.Greater(t, report.LatencyMs["max"], 0.0)
+
+	stdout.Reset()
+	code = run(context.Background(), []string{"-n", "3", ts.URL}, stdout, stderr)
+	assert.Equal(t, 0, code)
+	assert.True(t, strings.HasPrefix(stdout.String(), "Duration:"))
+	assert.Contains(t, stdout.String(), "Status codes: 400: 3\n")
+}
+
+func TestRunUsage(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	assert.Equal(t, 2, run(context.Background(), nil, io.Discard, stderr))
+	assert.Contains(t, stderr.String(), "usage: requests-bench")
+	stderr.Reset()
+	assert.Equal(t, 2, run(context.Background(), []string{"-H", "bad", "http://x"}, io.Discard, stderr))
+	assert.Contains(t, stderr.String(), `invalid header "bad"`)
+}
@@ -0,0 +1,108 @@
+// Command requests-test runs API test suites written as JSON, see
+// requests.LoadSuite, and reports the results in TAP or JUnit XML.
+//
+//	requests-test [-junit report.xml] [-var name=value] suite.json...
+//
+// The exit status is 1 if a test failed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	requests "github.com/hiroakis/go-requests"
+)
+
+// variables collects repeated -var flags
+type variables map[string]string
+
+func (v variables) String() string { return "" }
+
+func (v variables) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("invalid variable %q, want name=value", s)
+	}
+	v[name] = value
+	return nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("requests-test", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: requests-test [flags] suite.json...")
+		fs.PrintDefaults()
+	}
+	vars := variables{}
+	fs.Var(vars, "var", "set a variable `name=value`, can be repeated")
+	junit := fs.String("junit", "", "write a JUnit XML report to this file, - for stdout")
+	tap := fs.Bool("tap", true, "write TAP to stdout, unless the JUnit report goes there")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	s := requests.NewSession()
+	defer s.Close()
+	var results []*requests.SuiteResult
+	failed := false
+	for _, path := range fs.Args() {
+		suite, err := requests.LoadSuite(path)
+		if err != nil {
+			fmt.Fprintln(stderr, "requests-test:", err)
+			return 2
+		}
+		if suite.Variables == nil {
+			suite.Variables = map[string]string{}
+		}
+		for k, v := range vars {
+			suite.Variables[k] = v
+		}
+		r := s.RunSuite(suite)
+		failed = failed || r.Failed() > 0
+		results = append(results, r)
+	}
+
+	if *junit != "" {
+		if err := writeJUnit(*junit, stdout, results); err != nil {
+			fmt.Fprintln(stderr, "requests-test:", err)
+			return 2
+		}
+	}
+	if *tap && *junit != "-" {
+		requests.WriteTAP(stdout, results...)
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+func writeJUnit(path string, stdout io.Writer, results []*requests.SuiteResult) error {
+	if path == "-" {
+		return requests.WriteJUnit(stdout, results...)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := requests.WriteJUnit(f, results...); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	suite := filepath.Join(dir, "smoke.json")
+	os.WriteFile(suite, []byte(`{"base_url": "{{base}}", "tests": [
+		{"name": "health", "url": "/health", "expect": {"status": 200}},
+		{"name": "missing", "url": "/missing", "expect": {"status": 200}}
+	]}`), 0o644)
+	report := filepath.Join(dir, "report.xml")
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run([]string{"-var", "base=" + ts.URL, "-junit", report, suite}, stdout, stderr)
+	assert.Equal(t, 1, code, stderr.String())
+	assert.True(t, strings.HasPrefix(stdout.String(), "TAP version 13\n1..2\nok 1 - health\nnot ok 2 - missing\n"))
+	xml, err := os.ReadFile(report)
+	assert.NoError(t, err)
+	assert.Contains(t, string(xml), `<testsuite name="smoke" tests="2" failures="1" errors="0"`)
+
+	stdout.Reset()
+	code = run([]string{"-var", "base=" + ts.URL, "-junit", "-", suite}, stdout, stderr)
+	assert.Equal(t, 1, code)
+	assert.True(t, strings.HasPrefix(stdout.String(), "<?xml"))
+
+	assert.Equal(t, 2, run(nil, stdout, stderr))
+	assert.Equal(t, 2, run([]string{filepath.Join(dir, "none.json")}, stdout, stderr))
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	requests "github.com/hiroakis/go-requests"
+)
+
+// request is what the items on the command line describe
+type request struct {
+	form    bool
+	query   url.Values
+	headers http.Header
+	fields  []field
+	files   []field
+}
+
+type field struct {
+	name  string
+	value string
+	raw   bool // := JSON value
+}
+
+// item separators, longer ones first where they share a prefix
+var separators = []string{":=", "==", "=", ":", "@"}
+
+func parseItems(items []string, form bool) (*request, error) {
+	req := &request{form: form, query: url.Values{}, headers: http.Header{}}
+	for _, item := range items {
+		name, sep, value, ok := splitItem(item)
+		if !ok {
+			return nil, fmt.Errorf("invalid item %q", item)
+		}
+		switch sep {
+		case "==":
+			req.query.Add(name, value)
+		case ":":
+			if value == "" {
+				req.headers.Del(name)
+			} else {
+				req.headers.Add(name, value)
+			}
+		case "=":
+			req.fields = append(req.fields, field{name: name, value: value})
+		case ":=":
+			if form {
+				return nil, fmt.Errorf("%s: raw JSON fields cannot be sent as a form", item)
+			}
+			if !json.Valid([]byte(value)) {
+				return nil, fmt.Errorf("%s: invalid JSON", item)
+			}
+			req.fields = append(req.fields, field{name: name, value: value, raw: true})
+		case "@":
+			if !form {
+				return nil, fmt.Errorf("%s: file uploads need --form", item)
+			}
+			req.files = append(req.files, field{name: name, value: value})
+		}
+	}
+	return req, nil
+}
+
+// splitItem splits an item at its first separator
+func splitItem(item string) (string, string, string, bool) {
+	for i := 1; i < len(item); i++ {
+		for _, sep := range separators {
+			if strings.HasPrefix(item[i:], sep) {
+				return item[:i], sep, item[i+len(sep):], true
+			}
+		}
+	}
+	return "", "", "", false
+}
+
+func (r *request) hasBody() bool {
+	return len(r.fields) > 0 || len(r.files) > 0
+}
+
+func (r *request) params() (*requests.RequestParams, error) {
+	p := &requests.RequestParams{Headers: r.headers}
+	switch {
+	case len(r.files) > 0:
+		body := &bytes.Buffer{}
+		w := multipart.NewWriter(body)
+		for _, f := range r.fields {
+			w.WriteField(f.name, f.value)
+		}
+		for _, f := range r.files {
+			if err := writeFile(w, f); err != nil {
+				return nil, err
+			}
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		p.Data = body
+		r.headers.Set("Content-Type", w.FormDataContentType())
+	case len(r.fields) > 0 && r.form:
+		form := url.Values{}
+		for _, f := range r.fields {
+			form.Add(f.name, f.value)
+		}
+		p.Data = bytes.NewBufferString(form.Encode())
+		setDefault(r.headers, "Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	case len(r.fields) > 0:
+		obj := map[string]json.RawMessage{}
+		for _, f := range r.fields {
+			v := json.RawMessage(f.value)
+			if !f.raw {
+				v, _ = json.Marshal(f.value)
+			}
+			obj[f.name] = v
+		}
+		// Json is encoded by go-requests
+		p.Json = obj
+		setDefault(r.headers, "Content-Type", "application/json")
+		setDefault(r.headers, "Accept", "application/json, */*;q=0.5")
+	}
+	return p, nil
+}
+
+func writeFile(w *multipart.Writer, f field) error {
+	file, err := os.Open(f.value)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	part, err := w.CreateFormFile(f.name, filepath.Base(f.value))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, file)
+	return err
+}
+
+func setDefault(h http.Header, key, value string) {
+	if h.Get(key) == "" {
+		h.Set(key, value)
+	}
+}
@@ -0,0 +1,343 @@
+// Command requests is a command line HTTP client in the style of HTTPie,
+// built on go-requests.
+//
+//	requests [flags] [METHOD] URL [ITEM...]
+//
+// Items are
+//
+//	key==value    query string parameter
+//	Header:value  request header
+//	field=value   JSON string field, or form field with --form
+//	field:=json   raw JSON field, like count:=3 or tags:='["a","b"]'
+//	field@path    file upload, with --form
+//
+// The exit status is 0 for 2xx responses, 3 for 3xx, 4 for 4xx, 5 for 5xx,
+// 2 for timeouts and 1 for other errors.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	requests "github.com/hiroakis/go-requests"
+)
+
+const (
+	exitOK      = 0
+	exitError   = 1
+	exitTimeout = 2
+)
+
+type options struct {
+	form     bool
+	session  string
+	download bool
+	output   string
+	follow   bool
+	auth     string
+	timeout  time.Duration
+	print    string
+	pretty   string
+	verbose  bool
+	curl     bool
+	insecure bool
+	proxy    string
+	file     string
+	env      string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func newFlagSet(o *options, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("requests", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: requests [flags] [METHOD] URL [ITEM...]")
+		fmt.Fprintln(stderr, "       requests [flags] --file FILE.http [--env ENV] [NAME...]")
+		fmt.Fprintln(stderr, "\nitems: key==value (query), Header:value, field=value, field:=json, field@path (with --form)")
+		fmt.Fprintln(stderr, "\nflags:")
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&o.form, "form", false, "send fields as a form instead of JSON")
+	fs.StringVar(&o.session, "session", "", "name or path of a session keeping cookies and headers between runs")
+	fs.BoolVar(&o.download, "download", false, "save the response body to a file, resuming a partial download")
+	fs.StringVar(&o.output, "output", "", "file for --download")
+	fs.BoolVar(&o.follow, "follow", false, "follow redirects")
+	fs.StringVar(&o.auth, "auth", "", "basic auth credentials user:password")
+	fs.DurationVar(&o.timeout, "timeout", 0, "timeout of the whole request, like 10s")
+	fs.StringVar(&o.print, "print", "", "what to print: h for the response headers, b for the body (default hb on a terminal, b otherwise)")
+	fs.StringVar(&o.pretty, "pretty", "", "all, colors, format or none (default all on a terminal, none otherwise)")
+	fs.BoolVar(&o.verbose, "verbose", false, "print the request as a curl command to stderr")
+	fs.BoolVar(&o.curl, "curl", false, "print the request as a curl command instead of sending it")
+	fs.BoolVar(&o.insecure, "insecure", false, "skip TLS certificate verification")
+	fs.StringVar(&o.proxy, "proxy", "", "proxy URL, http://, socks5:// or socks5h://")
+	fs.StringVar(&o.file, "file", "", "run the requests of a .http file, or only the ones named by the arguments")
+	fs.StringVar(&o.env, "env", "", "environment of http-client.env.json next to the --file")
+	return fs
+}
+
+// parseArgs parses flags anywhere among the arguments, like HTTPie
+func parseArgs(args []string, stderr io.Writer) (*options, []string, error) {
+	o := &options{}
+	fs := newFlagSet(o, stderr)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, nil, err
+		}
+		if fs.NArg() == 0 {
+			return o, positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+var methods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	o, positional, err := parseArgs(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		return exitError
+	}
+
+	if o.file != "" {
+		return runHTTPFile(o, positional, stdout, stderr)
+	}
+
+	method := ""
+	if len(positional) > 1 && methods[strings.ToUpper(positional[0])] {
+		method, positional = strings.ToUpper(positional[0]), positional[1:]
+	}
+	if len(positional) == 0 {
+		fmt.Fprintln(stderr, "requests: missing URL")
+		return exitError
+	}
+	urlStr := normalizeURL(positional[0])
+	req, err := parseItems(positional[1:], o.form)
+	if err != nil {
+		fmt.Fprintln(stderr, "requests:", err)
+		return exitError
+	}
+	if method == "" {
+		method = http.MethodGet
+		if req.hasBody() {
+			method = http.MethodPost
+		}
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		fmt.Fprintln(stderr, "requests:", err)
+		return exitError
+	}
+	query := u.Query()
+	for k, vs := range req.query {
+		query[k] = append(query[k], vs...)
+	}
+	u.RawQuery = ""
+	urlStr = u.String()
+
+	var sess *session
+	if o.session != "" {
+		if sess, err = loadSession(o.session, u.Host); err != nil {
+			fmt.Fprintln(stderr, "requests:", err)
+			return exitError
+		}
+		sess.apply(req, u)
+	}
+
+	params, err := req.params()
+	if err != nil {
+		fmt.Fprintln(stderr, "requests:", err)
+		return exitError
+	}
+	if o.auth != "" {
+		user, pass, _ := strings.Cut(o.auth, ":")
+		params.Auth = &requests.Auth{Username: user, Password: pass}
+		if sess != nil {
+			sess.Auth = params.Auth
+		}
+	} else if sess != nil && sess.Auth != nil {
+		params.Auth = sess.Auth
+	}
+	if o.follow {
+		params.AllowRedirects = requests.Redirect().Allow()
+	} else {
+		params.AllowRedirects = requests.Redirect().NotAllow()
+	}
+	if o.timeout > 0 {
+		params.Timeout = &requests.Timeout{Read: o.timeout}
+	}
+	if sess != nil {
+		params.Cookies = sess.jar
+	}
+
+	s := newSession(o)
+	defer s.Close()
+
+	var qs *url.Values
+	if len(query) > 0 {
+		qs = &query
+	}
+	if o.curl || o.verbose {
+		cmd, err := s.AsCurl(method, urlStr, qs, params, nil)
+		if err != nil {
+			fmt.Fprintln(stderr, "requests:", err)
+			return exitError
+		}
+		if o.curl {
+			fmt.Fprintln(stdout, cmd)
+			return exitOK
+		}
+		fmt.Fprintln(stderr, cmd)
+	}
+
+	out := newPrinter(stdout, o.print, o.pretty)
+	if o.download {
+		if qs != nil {
+			urlStr += "?" + qs.Encode()
+		}
+		return download(s, urlStr, o.output, params, stderr)
+	}
+
+	resp, err := send(s, method, urlStr, qs, params)
+	if err != nil {
+		fmt.Fprintln(stderr, "requests:", err)
+		return errorStatus(err)
+	}
+	if sess != nil {
+		if err := sess.save(req, resp); err != nil {
+			fmt.Fprintln(stderr, "requests: saving session:", err)
+		}
+	}
+	out.response(resp)
+	return exitStatus(resp.StatusCode())
+}
+
+// runHTTPFile exits with the highest status of the responses, or 1 if a
+// request failed
+func runHTTPFile(o *options, names []string, stdout, stderr io.Writer) int {
+	f, err := requests.ParseHTTPFile(o.file)
+	if err != nil {
+		fmt.Fprintln(stderr, "requests:", err)
+		return exitError
+	}
+	runner := &requests.HTTPRunner{Output: stdout}
+	if o.env != "" {
+		if runner.Env, err = requests.LoadHTTPEnv(f.Dir, o.env); err != nil {
+			fmt.Fprintln(stderr, "requests:", err)
+			return exitError
+		}
+	}
+	s := newSession(o)
+	defer s.Close()
+	runner.Session = s
+	results, err := runner.Run(f, names...)
+	if err != nil {
+		fmt.Fprintln(stderr, "requests:", err)
+		return exitError
+	}
+	status := exitOK
+	for _, r := range results {
+		status = max(status, exitStatus(r.Response.StatusCode()))
+	}
+	return status
+}
+
+func newSession(o *options) *requests.Session {
+	s := requests.NewSession()
+	if o.insecure || o.proxy != "" {
+		s.Transport = &requests.Transport{Proxy: o.proxy}
+		if o.insecure {
+			s.Transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+	}
+	return s
+}
+
+func send(s *requests.Session, method, urlStr string, qs *url.Values, p *requests.RequestParams) (requests.Response, error) {
+	switch method {
+	case http.MethodHead:
+		return s.Head(urlStr, qs, p)
+	case http.MethodPost:
+		return s.Post(urlStr, qs, p)
+	case http.MethodPut:
+		return s.Put(urlStr, qs, p)
+	case http.MethodPatch:
+		return s.Patch(urlStr, qs, p)
+	case http.MethodDelete:
+		return s.Delete(urlStr, qs, p)
+	case http.MethodOptions:
+		return s.Options(urlStr, qs, p)
+	}
+	return s.Get(urlStr, qs, p)
+}
+
+func download(s *requests.Session, urlStr, output string, p *requests.RequestParams, stderr io.Writer) int {
+	if isTerminal(stderr) {
+		p.DownloadProgress = func(pr requests.Progress) {
+			if pr.Total > 0 {
+				fmt.Fprintf(stderr, "\r%d/%d bytes (%.0f%%)", pr.Transferred, pr.Total, float64(pr.Transferred)*100/float64(pr.Total))
+			} else {
+				fmt.Fprintf(stderr, "\r%d bytes", pr.Transferred)
+			}
+			if pr.Done {
+				fmt.Fprintln(stderr)
+			}
+		}
+	}
+	// redirects are followed, as the file is what is asked for
+	p.AllowRedirects = requests.Redirect().Allow()
+	path, err := s.Download(urlStr, output, p)
+	if err != nil {
+		fmt.Fprintln(stderr, "requests:", err)
+		return errorStatus(err)
+	}
+	fmt.Fprintln(stderr, "Downloaded to", path)
+	return exitOK
+}
+
+// normalizeURL accepts the shorthands :3000/path for localhost and a missing scheme
+func normalizeURL(s string) string {
+	if strings.HasPrefix(s, ":") {
+		s = "localhost" + s
+	}
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+	return s
+}
+
+func exitStatus(code int) int {
+	if code >= 300 && code < 600 {
+		return code / 100
+	}
+	return exitOK
+}
+
+func errorStatus(err error) int {
+	var ne net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout()) {
+		return exitTimeout
+	}
+	return exitError
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func runCmd(args ...string) (int, string, string) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(args, stdout, stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func echoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"method":  r.Method,
+			"query":   r.URL.Query(),
+			"headers": r.Header,
+			"body":    string(body),
+		})
+	}))
+}
+
+type echo struct {
+	Method  string
+	Query   map[string][]string
+	Headers http.Header
+	Body    string
+}
+
+func decodeEcho(t *testing.T, out string) echo {
+	var e echo
+	assert.NoError(t, json.Unmarshal([]byte(out), &e))
+	return e
+}
+
+func TestParseItems(t *testing.T) {
+	req, err := parseItems([]string{"q==go", "X-Token:abc", "name=John", "age:=30", "url=http://a/b?c=d", "tags:=[\"a\"]"}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"go"}, req.query["q"])
+	assert.Equal(t, "abc", req.headers.Get("X-Token"))
+	assert.Equal(t, []field{
+		{name: "name", value: "John"},
+		{name: "age", value: "30", raw: true},
+		{name: "url", value: "http://a/b?c=d"},
+		{name: "tags", value: `["a"]`, raw: true},
+	}, req.fields)
+
+	_, err = parseItems([]string{"count:=nope"}, false)
+	assert.Error(t, err)
+	_, err = parseItems([]string{"file@a.txt"}, false)
+	assert.Error(t, err)
+	_, err = parseItems([]string{"nothing"}, false)
+	assert.Error(t, err)
+}
+
+func TestRunJSON(t *testing.T) {
+	ts := echoServer()
+	defer ts.Close()
+
+	code, out, _ := runCmd(ts.URL+"/post", "name=John", "age:=30", "q==go", "X-Token:abc")
+	assert.Equal(t, 0, code)
+	e := decodeEcho(t, out)
+	assert.Equal(t, "POST", e.Method)
+	assert.Equal(t, []string{"go"}, e.Query["q"])
+	assert.Equal(t, "abc", e.Headers.Get("X-Token"))
+	assert.Equal(t, "application/json", e.Headers.Get("Content-Type"))
+	assert.JSONEq(t, `{"name":"John","age":30}`, e.Body)
+
+	code, out, _ = runCmd("put", ts.URL+"?a=1", "b==2")
+	assert.Equal(t, 0, code)
+	e = decodeEcho(t, out)
+	assert.Equal(t, "PUT", e.Method)
+	assert.Equal(t, map[string][]string{"a": {"1"}, "b": {"2"}}, e.Query)
+}
+
+func TestRunForm(t *testing.T) {
+	ts := echoServer()
+	defer ts.Close()
+
+	code, out, _ := runCmd("--form", ts.URL, "name=John Doe")
+	assert.Equal(t, 0, code)
+	e := decodeEcho(t, out)
+	assert.Equal(t, "application/x-www-form-urlencoded; charset=utf-8", e.Headers.Get("Content-Type"))
+	assert.Equal(t, "name=John+Doe", e.Body)
+
+	file := filepath.Join(t.TempDir(), "hello.txt")
+	os.WriteFile(file, []byte("hello"), 0o644)
+	code, out, _ = runCmd(ts.URL, "--form", "note=hi", "file@"+file)
+	assert.Equal(t, 0, code)
+	e = decodeEcho(t, out)
+	assert.True(t, strings.HasPrefix(e.Headers.Get("Content-Type"), "multipart/form-data; boundary="))
+	assert.Contains(t, e.Body, `filename="hello.txt"`)
+	assert.Contains(t, e.Body, "hello")
+}
+
+func TestRunPrint(t *testing.T) {
+	ts := echoServer()
+	defer ts.Close()
+
+	_, out, _ := runCmd("--print", "h", ts.URL)
+	assert.True(t, strings.HasPrefix(out, "HTTP 200 OK\n"))
+	assert.Contains(t, out, "Content-Type: application/json\n")
+	assert.NotContains(t, out, "method")
+
+	_, out, _ = runCmd("--pretty", "format", ts.URL)
+	assert.Contains(t, out, "\n  \"method\": \"GET\"")
+
+	_, out, _ = runCmd("--pretty", "all", ts.URL)
+	assert.Contains(t, out, colorKey+`"method"`+colorReset+": "+colorString+`"GET"`+colorReset)
+}
+
+func TestRunExitStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/missing", http.StatusFound)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/error":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer ts.Close()
+
+	for args, want := range map[string]int{
+		"/":                    0,
+		"/redirect":            3,
+		"--follow /redirect":   4,
+		"/missing":             4,
+		"/error":               5,
+		"--timeout 50ms /slow": 2,
+	} {
+		fields := strings.Fields(args)
+		fields[len(fields)-1] = ts.URL + fields[len(fields)-1]
+		code, _, _ := runCmd(fields...)
+		assert.Equal(t, want, code, args)
+	}
+
+	code, _, stderr := runCmd("http://127.0.0.1:1/")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "requests:")
+}
+
+func TestRunSession(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "s3cr3t", Path: "/"})
+			return
+		}
+		c, _ := r.Cookie("sid")
+		user, pass, _ := r.BasicAuth()
+		if c == nil || r.Header.Get("X-Team") != "blue" || user != "john" || pass != "pw" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	code, _, _ := runCmd("--session", path, "--auth", "john:pw", ts.URL+"/login", "X-Team:blue")
+	assert.Equal(t, 0, code)
+	code, _, _ = runCmd("--session", path, ts.URL+"/me")
+	assert.Equal(t, 0, code)
+	code, _, _ = runCmd(ts.URL + "/me")
+	assert.Equal(t, 4, code)
+
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	var s session
+	assert.NoError(t, json.Unmarshal(b, &s))
+	assert.Equal(t, map[string]string{"sid": "s3cr3t"}, s.Cookies)
+	assert.Equal(t, "blue", s.Headers["X-Team"])
+}
+
+func TestRunCurl(t *testing.T) {
+	code, out, _ := runCmd("--curl", "--auth", "john:pw", ":8080/users", "name=John")
+	assert.Equal(t, 0, code)
+	assert.True(t, strings.HasPrefix(out, `curl -X POST http://localhost:8080/users -u john:pw -H 'Accept: application/json, */*;q=0.5' -H 'Content-Type: application/json' `))
+	assert.True(t, strings.HasSuffix(out, ` --data-raw '{"name":"John"}`+"\n'\n"))
+}
+
+func TestRunDownload(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("file content"))
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "out.txt")
+	code, _, stderr := runCmd("--download", "--output", path, ts.URL+"/file.txt")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stderr, path)
+	b, _ := os.ReadFile(path)
+	assert.Equal(t, "file content", string(b))
+}
+
+func TestRunHTTPFile(t *testing.T) {
+	ts := echoServer()
+	defer ts.Close()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "http-client.env.json"), []byte(`{"test": {"base": "`+ts.URL+`"}}`), 0o644)
+	path := filepath.Join(dir, "api.http")
+	os.WriteFile(path, []byte("### list\nGET {{base}}/items\n\n### create\nPOST {{base}}/items\nContent-Type: application/json\n\n{\"name\": \"x\"}\n"), 0o644)
+
+	code, out, stderr := runCmd("--file", path, "--env", "test", "create")
+	assert.Equal(t, 0, code, stderr)
+	assert.True(t, strings.HasPrefix(out, "POST "+ts.URL+"/items\n200 OK\n"))
+	assert.Contains(t, out, `\"name\": \"x\"`)
+	assert.NotContains(t, out, "GET ")
+
+	code, _, stderr = runCmd("--file", path)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "undefined variable {{base}}")
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"sort"
+	"strings"
+
+	requests "github.com/hiroakis/go-requests"
+)
+
+// ANSI colors of the pretty printer
+const (
+	colorReset   = "\x1b[0m"
+	colorKey     = "\x1b[34m"
+	colorString  = "\x1b[32m"
+	colorNumber  = "\x1b[36m"
+	colorLiteral = "\x1b[35m"
+	colorHeader  = "\x1b[36m"
+)
+
+type printer struct {
+	w       io.Writer
+	headers bool
+	body    bool
+	color   bool
+	format  bool
+}
+
+// newPrinter prints headers and body with colors on a terminal and the raw
+// body otherwise, unless told otherwise by --print and --pretty
+func newPrinter(w io.Writer, print, pretty string) *printer {
+	tty := isTerminal(w)
+	if print == "" {
+		print = "b"
+		if tty {
+			print = "hb"
+		}
+	}
+	if pretty == "" {
+		pretty = "none"
+		if tty {
+			pretty = "all"
+		}
+	}
+	return &printer{
+		w:       w,
+		headers: strings.Contains(print, "h"),
+		body:    strings.Contains(print, "b"),
+		color:   pretty == "all" || pretty == "colors",
+		format:  pretty == "all" || pretty == "format",
+	}
+}
+
+func (p *printer) response(resp requests.Response) {
+	if p.headers {
+		p.line(colorHeader, "HTTP "+resp.Status())
+		h := resp.Headers()
+		keys := make([]string, 0, len(h))
+		for k := range h {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			for _, v := range h[k] {
+				if p.color {
+					fmt.Fprintf(p.w, "%s%s%s: %s\n", colorHeader, k, colorReset, v)
+				} else {
+					fmt.Fprintf(p.w, "%s: %s\n", k, v)
+				}
+			}
+		}
+		fmt.Fprintln(p.w)
+	}
+	if !p.body || resp.Raw() == nil {
+		return
+	}
+	body := resp.Content()
+	if p.format && isJSON(resp.Headers().Get("Content-Type"), body) {
+		p.json(body)
+		return
+	}
+	p.w.Write(body)
+	if p.headers && len(body) > 0 && body[len(body)-1] != '\n' {
+		fmt.Fprintln(p.w)
+	}
+}
+
+func (p *printer) line(color, s string) {
+	if p.color {
+		fmt.Fprintln(p.w, color+s+colorReset)
+	} else {
+		fmt.Fprintln(p.w, s)
+	}
+}
+
+func isJSON(contentType string, body []byte) bool {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	if mt != "application/json" && !strings.HasSuffix(mt, "+json") {
+		return false
+	}
+	return json.Valid(body)
+}
+
+// json indents the body and colors its tokens, keeping the order of keys
+func (p *printer) json(body []byte) {
+	buf := &bytes.Buffer{}
+	if err := json.Indent(buf, body, "", "  "); err != nil {
+		p.w.Write(body)
+		return
+	}
+	if !p.color {
+		buf.WriteByte('\n')
+		p.w.Write(buf.Bytes())
+		return
+	}
+	out := &strings.Builder{}
+	b := buf.Bytes()
+	for i := 0; i < len(b); {
+		switch c := b[i]; {
+		case c == '"':
+			j := i + 1
+			for ; j < len(b) && b[j] != '"'; j++ {
+				if b[j] == '\\' {
+					j++
+				}
+			}
+			j++
+			color := colorString
+			if j < len(b) && b[j] == ':' {
+				color = colorKey
+			}
+			out.WriteString(color + string(b[i:j]) + colorReset)
+			i = j
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i
+			for j < len(b) && strings.IndexByte("+-.0123456789eE", b[j]) >= 0 {
+				j++
+			}
+			out.WriteString(colorNumber + string(b[i:j]) + colorReset)
+			i = j
+		case c == 't' || c == 'f' || c == 'n':
+			j := i
+			for j < len(b) && b[j] >= 'a' && b[j] <= 'z' {
+				j++
+			}
+			out.WriteString(colorLiteral + string(b[i:j]) + colorReset)
+			i = j
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	fmt.Fprintln(p.w, out.String())
+}
+
+// isTerminal reports whether w is a terminal, not a file or a pipe
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	requests "github.com/hiroakis/go-requests"
+)
+
+// session keeps headers, cookies and credentials between runs in a JSON file
+type session struct {
+	Headers map[string]string `json:"headers"`
+	Cookies map[string]string `json:"cookies"`
+	Auth    *requests.Auth    `json:"auth,omitempty"`
+
+	path string
+	url  *url.URL
+	jar  *cookiejar.Jar
+}
+
+// headers which only make sense for one request
+var sessionSkipHeaders = []string{"Content-Type", "Content-Length", "If-"}
+
+// sessionPath is a path if name contains a path separator, otherwise the
+// session is kept per host in the user's config directory
+func sessionPath(name, host string) (string, error) {
+	if strings.ContainsRune(name, '/') || strings.ContainsRune(name, os.PathSeparator) {
+		return name, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	host = strings.ReplaceAll(host, ":", "_")
+	return filepath.Join(dir, "go-requests", "sessions", host, name+".json"), nil
+}
+
+func loadSession(name, host string) (*session, error) {
+	path, err := sessionPath(name, host)
+	if err != nil {
+		return nil, err
+	}
+	s := &session{Headers: map[string]string{}, Cookies: map[string]string{}, path: path}
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(b, s); err != nil {
+			return nil, err
+		}
+	}
+	if s.Headers == nil {
+		s.Headers = map[string]string{}
+	}
+	if s.Cookies == nil {
+		s.Cookies = map[string]string{}
+	}
+	s.jar, _ = cookiejar.New(nil)
+	return s, nil
+}
+
+// apply adds the headers and cookies of the session to req; headers on the
+// command line take precedence
+func (s *session) apply(req *request, u *url.URL) {
+	for k, v := range s.Headers {
+		if _, ok := req.headers[http.CanonicalHeaderKey(k)]; !ok {
+			req.headers.Set(k, v)
+		}
+	}
+	s.url = u
+	cookies := make([]*http.Cookie, 0, len(s.Cookies))
+	for k, v := range s.Cookies {
+		cookies = append(cookies, &http.Cookie{Name: k, Value: v, Path: "/"})
+	}
+	s.jar.SetCookies(&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}, cookies)
+}
+
+// save stores the headers sent with req and the cookies of the session
+func (s *session) save(req *request, resp requests.Response) error {
+	for k, vs := range req.headers {
+		if skipSessionHeader(k) || len(vs) == 0 {
+			continue
+		}
+		s.Headers[k] = vs[0]
+	}
+	for _, c := range s.jar.Cookies(s.url) {
+		s.Cookies[c.Name] = c.Value
+	}
+	// expired cookies are removed from the jar but not listed by it
+	for _, c := range resp.Cookies() {
+		if c.MaxAge < 0 || c.Value == "" {
+			delete(s.Cookies, c.Name)
+		}
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, append(b, '\n'), 0o600)
+}
+
+func skipSessionHeader(k string) bool {
+	for _, h := range sessionSkipHeaders {
+		if strings.HasPrefix(k, h) {
+			return true
+		}
+	}
+	return false
+}
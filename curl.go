@@ -0,0 +1,284 @@
+package requests
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const redacted = "REDACTED"
+
+// headers which are redacted by CurlOptions.Redact
+var secretHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+	"X-Amz-Security-Token",
+}
+
+// CurlOptions controls how requests are rendered as curl commands
+type CurlOptions struct {
+	// Redact replaces credentials with REDACTED: passwords, cookies, the
+	// Authorization header and other well known secret headers.
+	Redact bool
+	// RedactHeaders are more headers to redact.
+	RedactHeaders []string
+	// RedactParams are query parameters to redact, like "api_key".
+	RedactParams []string
+}
+
+// AsCurl renders a request as a curl command with the default Session
+func AsCurl(method, urlStr string, queryString *url.Values, r *RequestParams, o *CurlOptions) (string, error) {
+	return defaultSession.AsCurl(method, urlStr, queryString, r, o)
+}
+
+// AsCurl renders the request the Session would send for given method, urlStr,
+// queryString and RequestParams as a curl command, without sending it. It
+// includes the Session.Auth credentials and the proxy and TLS options of the
+// Session.Transport.
+func (s *Session) AsCurl(method, urlStr string, queryString *url.Values, r *RequestParams, o *CurlOptions) (string, error) {
+	req, err := s.client.newRequest(method, urlStr, queryString, r)
+	if err != nil {
+		return "", err
+	}
+	if s.Auth != nil && (r == nil || r.Auth == nil) {
+		if err := s.Auth.Authenticate(req); err != nil {
+			return "", err
+		}
+	}
+	return s.curl(req, r, o)
+}
+
+func (s *Session) curl(req *http.Request, r *RequestParams, o *CurlOptions) (string, error) {
+	if o == nil {
+		o = &CurlOptions{}
+	}
+	body, err := requestBody(req)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"curl"}
+	add := func(a ...string) {
+		for _, v := range a {
+			args = append(args, shellQuote(v))
+		}
+	}
+
+	switch {
+	case req.Method == http.MethodHead:
+		args = append(args, "-I")
+	case req.Method != http.MethodGet || len(body) > 0:
+		add("-X", req.Method)
+	}
+
+	u := *req.URL
+	if socket, ok := unixSocket(u.Host); ok {
+		add("--unix-socket", socket)
+		u.Scheme, u.Host = strings.TrimSuffix(u.Scheme, "+unix"), "localhost"
+	}
+	if o.Redact && len(o.RedactParams) > 0 {
+		q := u.Query()
+		for _, p := range o.RedactParams {
+			if q.Has(p) {
+				q.Set(p, redacted)
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	if u.User != nil && o.Redact {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+	}
+	add(u.String())
+
+	header := req.Header.Clone()
+	if r != nil && r.Auth != nil {
+		header.Del("Authorization")
+		password := r.Auth.Password
+		if o.Redact {
+			password = redacted
+		}
+		add("-u", r.Auth.Username+":"+password)
+	}
+	var cookies []string
+	if r != nil && r.Cookies != nil {
+		for _, c := range r.Cookies.Cookies(req.URL) {
+			cookies = append(cookies, c.Name+"="+c.Value)
+		}
+	}
+	if v := header.Get("Cookie"); v != "" {
+		cookies = append(cookies, v)
+		header.Del("Cookie")
+	}
+	if len(cookies) > 0 {
+		v := strings.Join(cookies, "; ")
+		if o.Redact {
+			v = redacted
+		}
+		add("-b", v)
+	}
+
+	secret := map[string]bool{}
+	for _, h := range secretHeaders {
+		secret[h] = true
+	}
+	for _, h := range o.RedactHeaders {
+		secret[http.CanonicalHeaderKey(h)] = true
+	}
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var form [][2]string
+	if len(body) > 0 && isMultipart(header.Get("Content-Type")) {
+		// a malformed body is sent as data instead
+		form, _ = multipartArgs(header.Get("Content-Type"), body)
+	}
+	for _, k := range keys {
+		if form != nil && k == "Content-Type" {
+			// curl writes its own boundary
+			continue
+		}
+		for _, v := range header[k] {
+			if o.Redact && secret[k] {
+				v = redactValue(v)
+			}
+			add("-H", k+": "+v)
+		}
+	}
+
+	if len(body) > 0 {
+		if form != nil {
+			for _, p := range form {
+				add(p[0], p[1])
+			}
+		} else if utf8.Valid(body) && !bytes.ContainsAny(body, "\x00") {
+			add("--data-raw", string(body))
+		} else {
+			args = append(args, "--data-binary", ansiQuote(body))
+		}
+	}
+
+	if r == nil || r.AllowRedirects == nil || *r.AllowRedirects {
+		args = append(args, "-L")
+	}
+	if r != nil && r.Timeout != nil {
+		if r.Timeout.Connect > 0 {
+			add("--connect-timeout", strconv.FormatFloat(r.Timeout.Connect.Seconds(), 'f', -1, 64))
+		}
+		if r.Timeout.Read > 0 {
+			add("--max-time", strconv.FormatFloat(r.Timeout.Read.Seconds(), 'f', -1, 64))
+		}
+	}
+	if t := s.Transport; t != nil {
+		if t.Proxy != "" {
+			proxy := t.Proxy
+			if p, err := url.Parse(t.Proxy); err == nil && p.User != nil && o.Redact {
+				if _, ok := p.User.Password(); ok {
+					p.User = url.UserPassword(p.User.Username(), redacted)
+				}
+				proxy = p.String()
+			}
+			add("-x", proxy)
+		}
+		if t.TLSClientConfig != nil && t.TLSClientConfig.InsecureSkipVerify {
+			args = append(args, "-k")
+		}
+		if t.DisableHTTP2 {
+			args = append(args, "--http1.1")
+		}
+		if t.Interface != "" {
+			add("--interface", t.Interface)
+		} else if t.LocalAddr != "" {
+			add("--interface", t.LocalAddr)
+		}
+	}
+	return strings.Join(args, " "), nil
+}
+
+// redactValue keeps the scheme of an Authorization like value
+func redactValue(v string) string {
+	if scheme, _, ok := strings.Cut(v, " "); ok && !strings.ContainsAny(scheme, "=:,") {
+		return scheme + " " + redacted
+	}
+	return redacted
+}
+
+func isMultipart(contentType string) bool {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	return mt == "multipart/form-data"
+}
+
+// multipartArgs renders the parts of a multipart/form-data body as curl
+// arguments. Files are referenced by their file name, and --form-string keeps
+// curl from reading values starting with @ or < as files.
+func multipartArgs(contentType string, body []byte) ([][2]string, error) {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	args := [][2]string{}
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return args, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p.FileName() != "" {
+			arg := p.FormName() + "=@" + p.FileName()
+			if ct := p.Header.Get("Content-Type"); ct != "" && ct != "application/octet-stream" {
+				arg += ";type=" + ct
+			}
+			args = append(args, [2]string{"-F", arg})
+			continue
+		}
+		v, err := io.ReadAll(p)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, [2]string{"--form-string", p.FormName() + "=" + string(v)})
+	}
+}
+
+// shellQuote quotes s for POSIX shells, only when needed
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@,+%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ansiQuote quotes binary data as a $'...' string of bash and zsh
+func ansiQuote(b []byte) string {
+	var sb strings.Builder
+	sb.WriteString("$'")
+	for _, c := range b {
+		switch {
+		case c == '\'' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c >= 0x20 && c < 0x7f:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, `\x%02x`, c)
+		}
+	}
+	sb.WriteString("'")
+	return sb.String()
+}
@@ -0,0 +1,426 @@
+package requests
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultDownloadName   = "download"
+	defaultSegmentRetries = 3
+)
+
+var errDownloadChanged = errors.New("go-requests: file changed during download")
+
+// Downloader downloads files like Download, with more options
+type Downloader struct {
+	// Session sends the requests. nil means the default Session.
+	Session *Session
+	// Checksum is verified after the download, like "sha256:<hex>" or "md5:<hex>".
+	Checksum string
+	// Segments splits the file into that many ranges fetched concurrently,
+	// if the server accepts byte ranges. A segmented download is not resumed.
+	Segments int
+	// SegmentRetries is how many times a failed segment is retried, 3 by default.
+	SegmentRetries int
+}
+
+// downloadMeta is saved next to a partial download to validate it when resuming
+type downloadMeta struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// Download saves the body of a GET request for urlStr to a file with the default Session
+func Download(urlStr, dst string, r *RequestParams) (string, error) {
+	return defaultSession.Download(urlStr, dst, r)
+}
+
+// Download saves the body of a GET request for urlStr to a file, see Downloader.Download
+func (s *Session) Download(urlStr, dst string, r *RequestParams) (string, error) {
+	d := &Downloader{Session: s}
+	return d.Download(urlStr, dst, r)
+}
+
+// Download saves the body of a GET request for urlStr to a file and returns its path.
+// If dst is empty or a directory, the file is named after Content-Disposition
+// or the URL.
+//
+// The body is streamed to a ".part" file which is renamed when complete. An
+// interrupted download is resumed with Range and If-Range, so it only continues
+// if the ETag or Last-Modified of the file has not changed.
+func (d *Downloader) Download(urlStr, dst string, r *RequestParams) (string, error) {
+	s := d.Session
+	if s == nil {
+		s = defaultSession
+	}
+	sum, err := newChecksum(d.Checksum)
+	if err != nil {
+		return "", err
+	}
+
+	dir, name := downloadTarget(dst)
+	part := partPath(dir, name, urlStr)
+	metaPath := part + ".meta"
+
+	if d.Segments > 1 {
+		head, ok, err := s.downloadSegments(urlStr, part, r, d)
+		if err != nil {
+			os.Remove(part)
+			return "", err
+		}
+		if ok {
+			return finishDownload(head, dir, name, part, sum)
+		}
+	}
+
+	var offset int64
+	meta, ok := readDownloadMeta(metaPath, urlStr)
+	if fi, err := os.Stat(part); err == nil && ok {
+		offset = fi.Size()
+	}
+
+	params := downloadParams(r)
+	if offset > 0 {
+		params.Headers.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		params.Headers.Set("If-Range", meta.validator())
+	}
+	resp, err := s.Get(urlStr, nil, params)
+	if err != nil {
+		return "", err
+	}
+	body := resp.Body()
+	defer body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode() == http.StatusPartialContent && offset > 0:
+		if start, err := contentRangeStart(resp.Headers().Get("Content-Range")); err != nil || start != offset {
+			return "", fmt.Errorf("go-requests: unexpected Content-Range %q", resp.Headers().Get("Content-Range"))
+		}
+		flags |= os.O_APPEND
+	case resp.StatusCode() == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// the partial file may already be complete
+		if resp.Headers().Get("Content-Range") != fmt.Sprintf("bytes */%d", offset) {
+			os.Remove(part)
+			os.Remove(metaPath)
+			return d.Download(urlStr, dst, r)
+		}
+		flags |= os.O_APPEND
+	case resp.StatusCode() >= 200 && resp.StatusCode() < 300:
+		flags |= os.O_TRUNC
+		offset = 0
+		meta = downloadMeta{
+			URL:          urlStr,
+			ETag:         resp.Headers().Get("ETag"),
+			LastModified: resp.Headers().Get("Last-Modified"),
+		}
+		if err := writeDownloadMeta(metaPath, meta); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("go-requests: download %s returned %s", urlStr, resp.Status())
+	}
+
+	f, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return "", err
+	}
+	if r != nil && r.DownloadProgress != nil {
+		total := resp.Len()
+		if total >= 0 {
+			total += offset
+		}
+		p := newProgress(r.DownloadProgress, r.ProgressInterval, total)
+		p.n = offset
+		body = &progressReader{ReadCloser: body, p: p}
+	}
+	if resp.StatusCode() != http.StatusRequestedRangeNotSatisfiable {
+		if _, err := io.Copy(f, body); err != nil {
+			f.Close()
+			return "", err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	saved, err := finishDownload(resp, dir, name, part, sum)
+	if _, serr := os.Stat(part); os.IsNotExist(serr) {
+		os.Remove(metaPath)
+	}
+	return saved, err
+}
+
+// finishDownload verifies the checksum and moves the complete part file to its place
+func finishDownload(resp Response, dir, name, part string, sum *checksum) (string, error) {
+	if sum != nil {
+		if err := sum.verify(part); err != nil {
+			os.Remove(part)
+			return "", err
+		}
+	}
+	if name == "" {
+		name = downloadName(resp)
+	}
+	dst := filepath.Join(dir, name)
+	if err := os.Rename(part, dst); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// downloadSegments fetches the file in d.Segments ranges at once. It returns
+// false if the server does not support ranges, to fall back to a single stream.
+func (s *Session) downloadSegments(urlStr, part string, r *RequestParams, d *Downloader) (Response, bool, error) {
+	var params RequestParams
+	if r != nil {
+		params = *r
+	}
+	params.Stream = false
+	// the progress is that of the segments
+	params.DownloadProgress, params.UploadProgress = nil, nil
+	head, err := s.Head(urlStr, nil, &params)
+	if err != nil {
+		return Response{}, false, err
+	}
+	size := head.Len()
+	if head.StatusCode() != http.StatusOK || size <= 0 ||
+		!strings.EqualFold(head.Headers().Get("Accept-Ranges"), "bytes") {
+		return head, false, nil
+	}
+	meta := downloadMeta{ETag: head.Headers().Get("ETag"), LastModified: head.Headers().Get("Last-Modified")}
+	if strings.HasPrefix(meta.ETag, "W/") {
+		meta.ETag = ""
+	}
+
+	f, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return Response{}, false, err
+	}
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return Response{}, false, err
+	}
+
+	n := int64(d.Segments)
+	if n > size {
+		n = size
+	}
+	retries := d.SegmentRetries
+	if retries == 0 {
+		retries = defaultSegmentRetries
+	}
+	var p *progress
+	if r != nil && r.DownloadProgress != nil {
+		p = newProgress(r.DownloadProgress, r.ProgressInterval, size)
+	}
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := int64(0); i < n; i++ {
+		wg.Add(1)
+		go func(i int64) {
+			defer wg.Done()
+			start, end := size*i/n, size*(i+1)/n-1
+			for attempt := 0; ; attempt++ {
+				written, err := s.downloadRange(urlStr, f, start, end, meta.validator(), r, p)
+				start += written
+				if err == nil || err == errDownloadChanged || attempt >= retries || requestContext(r).Err() != nil {
+					errs[i] = err
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := errors.Join(append(errs, f.Close())...); err != nil {
+		return Response{}, false, err
+	}
+	return head, true, nil
+}
+
+// downloadRange writes the bytes start-end of the file to f at the same offset
+func (s *Session) downloadRange(urlStr string, f *os.File, start, end int64, validator string, r *RequestParams, p *progress) (int64, error) {
+	params := downloadParams(r)
+	params.Headers.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	if validator != "" {
+		params.Headers.Set("If-Range", validator)
+	}
+	resp, err := s.Get(urlStr, nil, params)
+	if err != nil {
+		return 0, err
+	}
+	var body io.ReadCloser = resp.Body()
+	defer body.Close()
+	if resp.StatusCode() == http.StatusOK {
+		return 0, errDownloadChanged
+	}
+	if p != nil {
+		body = &progressReader{ReadCloser: body, p: p, part: true}
+	}
+	if resp.StatusCode() != http.StatusPartialContent {
+		return 0, fmt.Errorf("go-requests: download %s returned %s", urlStr, resp.Status())
+	}
+	if got, err := contentRangeStart(resp.Headers().Get("Content-Range")); err != nil || got != start {
+		return 0, fmt.Errorf("go-requests: unexpected Content-Range %q", resp.Headers().Get("Content-Range"))
+	}
+	want := end - start + 1
+	written, err := io.Copy(io.NewOffsetWriter(f, start), io.LimitReader(body, want))
+	if err == nil && written < want {
+		err = io.ErrUnexpectedEOF
+	}
+	return written, err
+}
+
+func downloadParams(r *RequestParams) *RequestParams {
+	var params RequestParams
+	if r != nil {
+		params = *r
+	}
+	params.Stream = true
+	// Download reports the progress of the whole file itself
+	params.DownloadProgress = nil
+	params.Headers = make(http.Header)
+	if r != nil && r.Headers != nil {
+		params.Headers = r.Headers.Clone()
+	}
+	return &params
+}
+
+func downloadTarget(p string) (string, string) {
+	if p == "" {
+		return ".", ""
+	}
+	if fi, err := os.Stat(p); err == nil && fi.IsDir() {
+		return p, ""
+	}
+	return filepath.Dir(p), filepath.Base(p)
+}
+
+// partPath names the partial file after the target, or after the URL while
+// the target name is not known yet
+func partPath(dir, name, urlStr string) string {
+	if name != "" {
+		return filepath.Join(dir, name+".part")
+	}
+	h := sha256.Sum256([]byte(urlStr))
+	return filepath.Join(dir, ".download-"+hex.EncodeToString(h[:8])+".part")
+}
+
+// downloadName returns a safe file name from Content-Disposition or the URL
+func downloadName(resp Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Headers().Get("Content-Disposition")); err == nil {
+		if name := safeFileName(params["filename"]); name != "" {
+			return name
+		}
+	}
+	if resp.Url() != nil {
+		if name := safeFileName(path.Base(resp.Url().Path)); name != "" {
+			return name
+		}
+	}
+	return defaultDownloadName
+}
+
+func safeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == ".." || name == "/" || strings.HasPrefix(name, ".") {
+		return ""
+	}
+	return name
+}
+
+func (m downloadMeta) validator() string {
+	if m.ETag != "" {
+		return m.ETag
+	}
+	return m.LastModified
+}
+
+func readDownloadMeta(p, urlStr string) (downloadMeta, bool) {
+	var m downloadMeta
+	b, err := os.ReadFile(p)
+	if err != nil || json.Unmarshal(b, &m) != nil || m.URL != urlStr {
+		return m, false
+	}
+	// a weak ETag can not be used with If-Range
+	if strings.HasPrefix(m.ETag, "W/") {
+		m.ETag = ""
+	}
+	return m, m.validator() != ""
+}
+
+func writeDownloadMeta(p string, m downloadMeta) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, b, 0644)
+}
+
+// contentRangeStart parses the first byte position of "bytes 100-199/200"
+func contentRangeStart(v string) (int64, error) {
+	v = strings.TrimPrefix(v, "bytes ")
+	i := strings.IndexByte(v, '-')
+	if i < 0 {
+		return 0, errors.New("go-requests: invalid Content-Range")
+	}
+	return strconv.ParseInt(v[:i], 10, 64)
+}
+
+type checksum struct {
+	algorithm string
+	expected  string
+	newHash   func() hash.Hash
+}
+
+func newChecksum(s string) (*checksum, error) {
+	if s == "" {
+		return nil, nil
+	}
+	algorithm, expected, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, fmt.Errorf("go-requests: checksum %q must be <algorithm>:<hex>", s)
+	}
+	c := &checksum{algorithm: strings.ToLower(algorithm), expected: strings.ToLower(expected)}
+	switch c.algorithm {
+	case "sha256":
+		c.newHash = sha256.New
+	case "md5":
+		c.newHash = md5.New
+	default:
+		return nil, fmt.Errorf("go-requests: unsupported checksum algorithm %q", algorithm)
+	}
+	return c, nil
+}
+
+func (c *checksum) verify(p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := c.newHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != c.expected {
+		return fmt.Errorf("go-requests: %s checksum mismatch: got %s, want %s", c.algorithm, got, c.expected)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package requests
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func downloadServer(content string, etag string) *httptest.Server {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Disposition", `attachment; filename="../report.txt"`)
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	})
+	return httptest.NewServer(handler)
+}
+
+func TestDownload(t *testing.T) {
+	ts := downloadServer("downloaded content", `"v1"`)
+	defer ts.Close()
+
+	dir := t.TempDir()
+	sum := sha256.Sum256([]byte("downloaded content"))
+	d := &Downloader{Checksum: "sha256:" + hex.EncodeToString(sum[:])}
+	p, err := d.Download(ts.URL+"/files/1", dir, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "report.txt"), p)
+	b, _ := os.ReadFile(p)
+	assert.Equal(t, "downloaded content", string(b))
+
+	entries, _ := os.ReadDir(dir)
+	assert.Equal(t, 1, len(entries), "partial files should be removed")
+}
+
+func TestDownloadResume(t *testing.T) {
+	var ranges []string
+	content := "0123456789abcdefghij"
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range")+" "+r.Header.Get("If-Range"))
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "out.bin")
+	os.WriteFile(dst+".part", []byte(content[:10]), 0644)
+	writeDownloadMeta(dst+".part.meta", downloadMeta{URL: ts.URL, ETag: `"v1"`})
+
+	p, err := Download(ts.URL, dst, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, dst, p)
+	b, _ := os.ReadFile(dst)
+	assert.Equal(t, content, string(b))
+	assert.Equal(t, []string{`bytes=10- "v1"`}, ranges)
+}
+
+func TestDownloadResumeChanged(t *testing.T) {
+	ts := downloadServer("new content", `"v2"`)
+	defer ts.Close()
+
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "out.bin")
+	os.WriteFile(dst+".part", []byte("old"), 0644)
+	writeDownloadMeta(dst+".part.meta", downloadMeta{URL: ts.URL, ETag: `"v1"`})
+
+	_, err := Download(ts.URL, dst, nil)
+	assert.Nil(t, err)
+	b, _ := os.ReadFile(dst)
+	assert.Equal(t, "new content", string(b))
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	ts := downloadServer("content", `"v1"`)
+	defer ts.Close()
+
+	dir := t.TempDir()
+	_, err := (&Downloader{Checksum: "md5:00"}).Download(ts.URL, filepath.Join(dir, "out"), nil)
+	assert.NotNil(t, err)
+	entries, _ := os.ReadDir(dir)
+	assert.Equal(t, 0, len(entries))
+}
+
+func TestDownloadSegments(t *testing.T) {
+	content := strings.Repeat("0123456789", 1000)
+	var (
+		mu     sync.Mutex
+		ranges []string
+		failed bool
+	)
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if r.Method == http.MethodGet {
+			ranges = append(ranges, r.Header.Get("Range"))
+		}
+		// the first request for the second segment breaks off
+		breakOff := r.Header.Get("Range") == "bytes=2500-4999" && !failed
+		if breakOff {
+			failed = true
+		}
+		mu.Unlock()
+		if breakOff {
+			w.Header().Set("Content-Range", "bytes 2500-4999/10000")
+			w.Header().Set("Content-Length", "2500")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte(content[2500:3000]))
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	dst := filepath.Join(t.TempDir(), "big.bin")
+	p, err := (&Downloader{Segments: 4}).Download(ts.URL, dst, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, dst, p)
+	b, _ := os.ReadFile(dst)
+	assert.Equal(t, content, string(b))
+	assert.Equal(t, 5, len(ranges))
+	assert.Contains(t, ranges, "bytes=3000-4999")
+}
+
+func TestDownloadSegmentsFallback(t *testing.T) {
+	var gets []string
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets = append(gets, r.Header.Get("Range"))
+		}
+		w.Write([]byte("no ranges here"))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	dst := filepath.Join(t.TempDir(), "small.bin")
+	_, err := (&Downloader{Segments: 4}).Download(ts.URL, dst, nil)
+	assert.Nil(t, err)
+	b, _ := os.ReadFile(dst)
+	assert.Equal(t, "no ranges here", string(b))
+	assert.Equal(t, []string{""}, gets)
+}
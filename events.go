@@ -0,0 +1,220 @@
+package requests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultEventRetry = 3 * time.Second
+	maxEventLineSize  = 1 << 20
+)
+
+// Event is a Server-Sent Event. Event is "message" unless the server named it.
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// Events subscribes to a text/event-stream with given urlStr, queryString and RequestParams
+func Events(urlStr string, queryString *url.Values, r *RequestParams) (chan Event, chan error) {
+	return defaultSession.Events(urlStr, queryString, r)
+}
+
+// Events subscribes to a text/event-stream with given urlStr, queryString and RequestParams.
+// When the connection is lost, it reconnects after the retry interval sent by
+// the server (3 seconds by default) with the Last-Event-ID header.
+//
+// Events are delivered until RequestParams.Context is done, the server
+// answers 204 No Content, or an error which can not be recovered by
+// reconnecting happens. Then the error, if any, is sent and both channels are closed.
+func (s *Session) Events(urlStr string, queryString *url.Values, r *RequestParams) (chan Event, chan error) {
+	eventCh := make(chan Event)
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			close(eventCh)
+			close(errCh)
+		}()
+
+		ctx := requestContext(r)
+		var params RequestParams
+		if r != nil {
+			params = *r
+		}
+		params.Stream = true
+		es := &eventStream{ctx: ctx, ch: eventCh, retry: defaultEventRetry}
+		for {
+			params.Headers = make(http.Header)
+			if r != nil && r.Headers != nil {
+				params.Headers = r.Headers.Clone()
+			}
+			params.Headers.Set("Accept", "text/event-stream")
+			params.Headers.Set("Cache-Control", "no-cache")
+			if es.lastID != "" {
+				params.Headers.Set("Last-Event-ID", es.lastID)
+			}
+
+			resp, err := s.Get(urlStr, queryString, &params)
+			if err != nil && !reconnectable(err) {
+				if ctx.Err() == nil {
+					errCh <- err
+				}
+				return
+			}
+			if err == nil {
+				body := resp.Body()
+				err = checkEventStream(resp)
+				if err == nil {
+					es.read(body)
+				}
+				body.Close()
+				if resp.StatusCode() == http.StatusNoContent {
+					return
+				}
+				if err != nil {
+					errCh <- err
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(es.retry):
+			}
+		}
+	}()
+	return eventCh, errCh
+}
+
+// reconnectable reports whether err is a failure of the connection to the
+// server, rather than of the request itself
+func reconnectable(err error) bool {
+	var ue *url.Error
+	if !errors.As(err, &ue) || ue.Op == "parse" {
+		return false
+	}
+	var ne net.Error
+	return errors.As(ue.Err, &ne) || errors.Is(ue.Err, io.EOF) || errors.Is(ue.Err, io.ErrUnexpectedEOF)
+}
+
+func checkEventStream(resp Response) error {
+	if resp.StatusCode() == http.StatusNoContent {
+		return nil
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return fmt.Errorf("go-requests: event stream %s returned %s", resp.Url(), resp.Status())
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Headers().Get("Content-Type"))
+	if mediaType != "text/event-stream" {
+		return fmt.Errorf("go-requests: event stream %s has Content-Type %q", resp.Url(), mediaType)
+	}
+	return nil
+}
+
+type eventStream struct {
+	ctx    context.Context
+	ch     chan Event
+	lastID string
+	retry  time.Duration
+}
+
+// read parses events as described in the HTML Living Standard, 9.2.6
+// "Interpreting an event stream", until body ends.
+func (es *eventStream) read(body io.Reader) {
+	sc := bufio.NewScanner(body)
+	sc.Buffer(make([]byte, 4096), maxEventLineSize)
+	sc.Split(scanEventLines)
+
+	var (
+		data      strings.Builder
+		eventType string
+		hasData   bool
+	)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			if hasData {
+				ev := Event{ID: es.lastID, Event: eventType, Data: data.String(), Retry: es.retry}
+				if ev.Event == "" {
+					ev.Event = "message"
+				}
+				select {
+				case es.ch <- ev:
+				case <-es.ctx.Done():
+					return
+				}
+			}
+			data.Reset()
+			eventType, hasData = "", false
+			continue
+		}
+		if line[0] == ':' {
+			continue
+		}
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				es.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				es.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+// scanEventLines splits lines ended by CRLF, LF or CR
+func scanEventLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		// wait to see whether LF follows CR
+		return 0, nil, nil
+	}
+	if atEOF {
+		// an incomplete event at the end of the stream is discarded
+		return len(data), nil, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,102 @@
+package requests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvents(t *testing.T) {
+	var lastIDs []string
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastIDs = append(lastIDs, r.Header.Get("Last-Event-ID"))
+		if r.Header.Get("Last-Event-ID") == "2" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": comment\nretry: 10\n\n")
+		fmt.Fprint(w, "id: 1\ndata: first\ndata: line\n\n")
+		fmt.Fprint(w, "id: 2\r\nevent: update\r\ndata:second\r\n\r\n")
+		fmt.Fprint(w, "data: incomplete")
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	eventCh, errCh := Events(ts.URL, nil, nil)
+	var events []Event
+	for ev := range eventCh {
+		events = append(events, ev)
+	}
+	assert.Nil(t, <-errCh)
+
+	assert.Equal(t, []Event{
+		{ID: "1", Event: "message", Data: "first\nline", Retry: 10 * time.Millisecond},
+		{ID: "2", Event: "update", Data: "second", Retry: 10 * time.Millisecond},
+	}, events)
+	assert.Equal(t, []string{"", "2"}, lastIDs)
+}
+
+func TestEventsCancel(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: ping\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	eventCh, errCh := Events(ts.URL, nil, &RequestParams{Context: ctx})
+	ev := <-eventCh
+	assert.Equal(t, "ping", ev.Data)
+	cancel()
+	for range eventCh {
+	}
+	assert.Nil(t, <-errCh)
+}
+
+func TestEventsError(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("not a stream"))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	eventCh, errCh := Events(ts.URL, nil, nil)
+	for range eventCh {
+	}
+	err := <-errCh
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "text/plain"))
+}
+
+func TestEventsRequestError(t *testing.T) {
+	eventCh, errCh := Events("ftp://example.com/events", nil, nil)
+	for range eventCh {
+	}
+	err := <-errCh
+	assert.ErrorContains(t, err, "unsupported protocol scheme")
+}
+
+func TestEventsReconnect(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := ts.URL
+	ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	// the connection refused is retried until the context is done
+	eventCh, errCh := Events(url, nil, &RequestParams{Context: ctx})
+	for range eventCh {
+	}
+	assert.Nil(t, <-errCh)
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+
+	requests "github.com/hiroakis/go-requests"
+)
+
+func main() {
+	// saved as ./image.png; run it again after an interruption to resume
+	path, err := requests.Download("https://httpbin.org/image/png", "image.png", nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(path)
+}
@@ -0,0 +1,620 @@
+package requests
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CurlRequest is a request parsed from a curl command by FromCurl
+type CurlRequest struct {
+	Method string
+	// URL includes the query string.
+	URL    string
+	Params *RequestParams
+	// Insecure is set by -k and Proxy by -x. Send applies them to a Session of its own.
+	Insecure bool
+	Proxy    string
+
+	form []curlFormPart
+}
+
+type curlFormPart struct {
+	name        string
+	value       string
+	file        string // file name sent
+	path        string // file read
+	contentType string
+}
+
+// curl options which take no argument and change nothing here
+var curlIgnoredFlags = map[string]bool{
+	"-s": true, "--silent": true, "-S": true, "--show-error": true,
+	"-v": true, "--verbose": true, "-i": true, "--include": true,
+	"-f": true, "--fail": true, "--fail-with-body": true, "-g": true, "--globoff": true,
+	"-#": true, "--progress-bar": true, "-N": true, "--no-buffer": true,
+	"--compressed": true, "--http1.1": true, "--http2": true, "-L": true, "--location": true,
+	"-k": true, "--insecure": true, "-G": true, "--get": true, "-I": true, "--head": true,
+}
+
+// curl options which take an argument that is not used here
+var curlIgnoredOptions = map[string]bool{
+	"-o": true, "--output": true, "-w": true, "--write-out": true,
+	"-c": true, "--cookie-jar": true, "--retry": true, "--retry-delay": true,
+	"--retry-max-time": true, "-D": true, "--dump-header": true,
+}
+
+// curl options which take an argument
+var curlOptions = map[string]bool{
+	"-X": true, "--request": true, "-H": true, "--header": true,
+	"-d": true, "--data": true, "--data-raw": true, "--data-binary": true, "--data-ascii": true,
+	"--data-urlencode": true, "--json": true, "-F": true, "--form": true, "--form-string": true,
+	"-u": true, "--user": true, "-b": true, "--cookie": true, "-A": true, "--user-agent": true,
+	"-e": true, "--referer": true, "-m": true, "--max-time": true, "--connect-timeout": true,
+	"-x": true, "--proxy": true, "--url": true,
+}
+
+// FromCurl parses a curl command line, as documented by many APIs, into a
+// request. It understands -X, -H, -d and the other --data options, -G, -F,
+// -u, -b, -A, -e, -k, -x, -L, --json, --max-time and --connect-timeout.
+// Files given with @ are read when the command is parsed. Without -L,
+// redirects are not followed, like curl.
+func FromCurl(cmd string) (*CurlRequest, error) {
+	args, err := splitShell(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 0 && (args[0] == "curl" || strings.HasSuffix(args[0], "/curl")) {
+		args = args[1:]
+	}
+
+	c := &CurlRequest{Params: &RequestParams{Headers: http.Header{}}}
+	var (
+		data                      []string
+		get, head, follow, isJSON bool
+		readTimeout, connTimeout  time.Duration
+	)
+	for i := 0; i < len(args); i++ {
+		opt, val := args[i], ""
+		if !strings.HasPrefix(opt, "-") || opt == "-" {
+			if c.URL != "" {
+				return nil, fmt.Errorf("go-requests: more than one URL in curl command: %s", opt)
+			}
+			c.URL = opt
+			continue
+		}
+		if !strings.HasPrefix(opt, "--") && len(opt) > 2 {
+			// combined short options like -sSL or -XPOST
+			expanded := []string{}
+			for j := 1; j < len(opt); j++ {
+				short := "-" + opt[j:j+1]
+				expanded = append(expanded, short)
+				if curlOptions[short] || curlIgnoredOptions[short] {
+					if j+1 < len(opt) {
+						expanded = append(expanded, opt[j+1:])
+					}
+					break
+				}
+			}
+			args = append(args[:i], append(expanded, args[i+1:]...)...)
+			opt = args[i]
+		}
+		if curlOptions[opt] || curlIgnoredOptions[opt] {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("go-requests: curl option %s needs an argument", opt)
+			}
+			i++
+			val = args[i]
+		} else if !curlIgnoredFlags[opt] {
+			return nil, fmt.Errorf("go-requests: unsupported curl option %s", opt)
+		}
+
+		switch opt {
+		case "-X", "--request":
+			c.Method = strings.ToUpper(val)
+		case "--url":
+			c.URL = val
+		case "-H", "--header":
+			if name, value, ok := strings.Cut(val, ":"); ok {
+				if value = strings.TrimSpace(value); value != "" {
+					c.Params.Headers.Add(strings.TrimSpace(name), value)
+				}
+			} else if name, ok := strings.CutSuffix(val, ";"); ok {
+				// "Name;" sends an empty header
+				c.Params.Headers[textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))] = []string{""}
+			}
+		case "-d", "--data", "--data-ascii", "--data-binary", "--json":
+			if strings.HasPrefix(val, "@") {
+				b, err := readCurlFile(val[1:])
+				if err != nil {
+					return nil, err
+				}
+				if opt == "-d" || opt == "--data" || opt == "--data-ascii" {
+					// curl drops newlines from files given to -d
+					b = bytes.ReplaceAll(bytes.ReplaceAll(b, []byte("\r"), nil), []byte("\n"), nil)
+				}
+				val = string(b)
+			}
+			data = append(data, val)
+			isJSON = isJSON || opt == "--json"
+		case "--data-raw":
+			data = append(data, val)
+		case "--data-urlencode":
+			v, err := curlURLEncode(val)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, v)
+		case "-F", "--form", "--form-string":
+			p, err := parseCurlForm(val, opt == "--form-string")
+			if err != nil {
+				return nil, err
+			}
+			c.form = append(c.form, p)
+		case "-u", "--user":
+			user, pass, _ := strings.Cut(val, ":")
+			c.Params.Auth = &Auth{Username: user, Password: pass}
+		case "-b", "--cookie":
+			if !strings.Contains(val, "=") {
+				return nil, fmt.Errorf("go-requests: cookie files are not supported: %s", val)
+			}
+			c.Params.Headers.Add("Cookie", val)
+		case "-A", "--user-agent":
+			c.Params.Headers.Set("User-Agent", val)
+		case "-e", "--referer":
+			c.Params.Headers.Set("Referer", val)
+		case "-m", "--max-time", "--connect-timeout":
+			secs, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				return nil, fmt.Errorf("go-requests: invalid %s %q", opt, val)
+			}
+			if opt == "--connect-timeout" {
+				connTimeout = time.Duration(secs * float64(time.Second))
+			} else {
+				readTimeout = time.Duration(secs * float64(time.Second))
+			}
+		case "-x", "--proxy":
+			c.Proxy = val
+		case "-k", "--insecure":
+			c.Insecure = true
+		case "-L", "--location":
+			follow = true
+		case "-G", "--get":
+			get = true
+		case "-I", "--head":
+			head = true
+		}
+	}
+	if c.URL == "" {
+		return nil, errors.New("go-requests: no URL in curl command")
+	}
+	if !strings.Contains(c.URL, "://") {
+		// curl defaults to http
+		c.URL = "http://" + c.URL
+	}
+
+	switch {
+	case len(c.form) > 0:
+		if len(data) > 0 {
+			return nil, errors.New("go-requests: curl -F cannot be used with -d")
+		}
+		body, contentType, err := c.multipartBody()
+		if err != nil {
+			return nil, err
+		}
+		c.Params.Data = body
+		c.Params.Headers.Set("Content-Type", contentType)
+		c.defaultMethod(http.MethodPost)
+	case len(data) > 0 && get:
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			return nil, err
+		}
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += strings.Join(data, "&")
+		c.URL = u.String()
+	case len(data) > 0:
+		c.Params.Data = bytes.NewBufferString(strings.Join(data, "&"))
+		if c.Params.Headers.Get("Content-Type") == "" {
+			if isJSON {
+				c.Params.Headers.Set("Content-Type", "application/json")
+			} else {
+				c.Params.Headers.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+		}
+		if isJSON && c.Params.Headers.Get("Accept") == "" {
+			c.Params.Headers.Set("Accept", "application/json")
+		}
+		c.defaultMethod(http.MethodPost)
+	}
+	if head {
+		c.defaultMethod(http.MethodHead)
+	}
+	c.defaultMethod(http.MethodGet)
+
+	if !follow {
+		c.Params.AllowRedirects = Redirect().NotAllow()
+	}
+	if readTimeout > 0 || connTimeout > 0 {
+		c.Params.Timeout = &Timeout{Read: readTimeout, Connect: connTimeout}
+	}
+	if len(c.Params.Headers) == 0 {
+		c.Params.Headers = nil
+	}
+	return c, nil
+}
+
+func (c *CurlRequest) defaultMethod(method string) {
+	if c.Method == "" {
+		c.Method = method
+	}
+}
+
+func readCurlFile(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// curlURLEncode handles the forms of --data-urlencode: content, =content,
+// name=content, @file and name@file
+func curlURLEncode(v string) (string, error) {
+	if i := strings.IndexAny(v, "=@"); i >= 0 {
+		name, content := v[:i], v[i+1:]
+		if v[i] == '@' {
+			b, err := readCurlFile(content)
+			if err != nil {
+				return "", err
+			}
+			content = string(b)
+		}
+		if name == "" {
+			return url.QueryEscape(content), nil
+		}
+		return name + "=" + url.QueryEscape(content), nil
+	}
+	return url.QueryEscape(v), nil
+}
+
+// parseCurlForm parses name=value, name=@file and name=<file with an optional
+// ;type= or ;filename=
+func parseCurlForm(v string, literal bool) (curlFormPart, error) {
+	name, value, ok := strings.Cut(v, "=")
+	if !ok {
+		return curlFormPart{}, fmt.Errorf("go-requests: invalid curl form %q", v)
+	}
+	p := curlFormPart{name: name, value: value}
+	if literal || (!strings.HasPrefix(value, "@") && !strings.HasPrefix(value, "<")) {
+		return p, nil
+	}
+	fields := strings.Split(value[1:], ";")
+	path := fields[0]
+	filename := filepath.Base(path)
+	for _, f := range fields[1:] {
+		k, v, _ := strings.Cut(strings.TrimSpace(f), "=")
+		switch k {
+		case "type":
+			p.contentType = v
+		case "filename":
+			filename = v
+		}
+	}
+	b, err := readCurlFile(path)
+	if err != nil {
+		return p, err
+	}
+	p.value = string(b)
+	if value[0] == '@' {
+		p.file, p.path = filename, path
+	}
+	return p, nil
+}
+
+func (c *CurlRequest) multipartBody() (*bytes.Buffer, string, error) {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	for _, p := range c.form {
+		if p.file == "" && p.contentType == "" {
+			if err := w.WriteField(p.name, p.value); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+		h := make(textproto.MIMEHeader)
+		disposition := fmt.Sprintf(`form-data; name=%q`, p.name)
+		if p.file != "" {
+			disposition += fmt.Sprintf(`; filename=%q`, p.file)
+		}
+		h.Set("Content-Disposition", disposition)
+		h.Set("Content-Type", defaultString(p.contentType, "application/octet-stream"))
+		pw, err := w.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		pw.Write([]byte(p.value))
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return body, w.FormDataContentType(), nil
+}
+
+// Send sends the request with the default Session, or with a Session of its
+// own when it has Insecure or Proxy
+func (c *CurlRequest) Send() (Response, error) {
+	s := defaultSession
+	if c.Insecure || c.Proxy != "" {
+		s = NewSession()
+		s.Transport = &Transport{Proxy: c.Proxy}
+		if c.Insecure {
+			s.Transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+		defer s.Close()
+	}
+	params := *c.Params
+	if c.Params.Data != nil {
+		// the body can be sent again
+		params.Data = bytes.NewBuffer(c.Params.Data.Bytes())
+	}
+	return s.send(c.Method, c.URL, nil, &params)
+}
+
+var goMethods = map[string]string{
+	http.MethodHead:    "Head",
+	http.MethodGet:     "Get",
+	http.MethodPost:    "Post",
+	http.MethodPut:     "Put",
+	http.MethodPatch:   "Patch",
+	http.MethodDelete:  "Delete",
+	http.MethodOptions: "Options",
+}
+
+// GoCode returns a Go program making the request with go-requests
+func (c *CurlRequest) GoCode() (string, error) {
+	fn, ok := goMethods[c.Method]
+	if !ok {
+		return "", fmt.Errorf("go-requests: no function for method %s", c.Method)
+	}
+	imports := map[string]bool{"fmt": true, "log": true, "github.com/hiroakis/go-requests": true}
+	var b strings.Builder
+	var fields []string
+	p := c.Params
+
+	if len(c.form) > 0 {
+		imports["bytes"], imports["mime/multipart"] = true, true
+		b.WriteString("body := &bytes.Buffer{}\nw := multipart.NewWriter(body)\n")
+		for _, f := range c.form {
+			switch {
+			case f.file != "":
+				imports["os"] = true
+				fmt.Fprintf(&b, "{\npart, err := w.CreateFormFile(%q, %q)\nif err != nil {\nlog.Fatal(err)\n}\n", f.name, f.file)
+				fmt.Fprintf(&b, "content, err := os.ReadFile(%q)\nif err != nil {\nlog.Fatal(err)\n}\npart.Write(content)\n}\n", f.path)
+			default:
+				fmt.Fprintf(&b, "w.WriteField(%q, %q)\n", f.name, f.value)
+			}
+		}
+		b.WriteString("w.Close()\n")
+		fields = append(fields, "Data: body")
+	} else if p.Data != nil {
+		imports["bytes"] = true
+		fields = append(fields, fmt.Sprintf("Data: bytes.NewBufferString(%s)", goString(p.Data.String())))
+	}
+
+	if len(p.Headers) > 0 {
+		imports["net/http"] = true
+		names := make([]string, 0, len(p.Headers))
+		for k := range p.Headers {
+			if k == "Content-Type" && len(c.form) > 0 {
+				continue
+			}
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		var h strings.Builder
+		h.WriteString("Headers: http.Header{\n")
+		for _, k := range names {
+			quoted := make([]string, len(p.Headers[k]))
+			for i, v := range p.Headers[k] {
+				quoted[i] = strconv.Quote(v)
+			}
+			fmt.Fprintf(&h, "%q: {%s},\n", k, strings.Join(quoted, ", "))
+		}
+		if len(c.form) > 0 {
+			h.WriteString(`"Content-Type": {w.FormDataContentType()},` + "\n")
+		}
+		h.WriteString("}")
+		fields = append(fields, h.String())
+	}
+	if p.Auth != nil {
+		fields = append(fields, fmt.Sprintf("Auth: &requests.Auth{Username: %q, Password: %q}", p.Auth.Username, p.Auth.Password))
+	}
+	if p.Timeout != nil {
+		imports["time"] = true
+		fields = append(fields, fmt.Sprintf("Timeout: &requests.Timeout{Connect: %s, Read: %s}", goDuration(p.Timeout.Connect), goDuration(p.Timeout.Read)))
+	}
+	if p.AllowRedirects != nil && !*p.AllowRedirects {
+		fields = append(fields, "AllowRedirects: requests.Redirect().NotAllow()")
+	}
+
+	session := "requests"
+	var prelude strings.Builder
+	if c.Insecure || c.Proxy != "" {
+		session = "s"
+		prelude.WriteString("s := requests.NewSession()\ndefer s.Close()\ns.Transport = &requests.Transport{\n")
+		if c.Proxy != "" {
+			fmt.Fprintf(&prelude, "Proxy: %q,\n", c.Proxy)
+		}
+		if c.Insecure {
+			imports["crypto/tls"] = true
+			prelude.WriteString("TLSClientConfig: &tls.Config{InsecureSkipVerify: true},\n")
+		}
+		prelude.WriteString("}\n")
+	}
+
+	var s strings.Builder
+	s.WriteString("package main\n\nimport (\n")
+	names := make([]string, 0, len(imports))
+	for k := range imports {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		fmt.Fprintf(&s, "%q\n", k)
+	}
+	s.WriteString(")\n\nfunc main() {\n")
+	s.WriteString(prelude.String())
+	s.WriteString(b.String())
+	params := "nil"
+	if len(fields) > 0 {
+		params = "&requests.RequestParams{\n" + strings.Join(fields, ",\n") + ",\n}"
+	}
+	fmt.Fprintf(&s, "resp, err := %s.%s(%q, nil, %s)\n", session, fn, c.URL, params)
+	s.WriteString("if err != nil {\nlog.Fatal(err)\n}\nfmt.Println(resp.Status())\nfmt.Println(resp.Text())\n}\n")
+
+	out, err := format.Source([]byte(s.String()))
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// goString quotes s as a raw string literal when it reads better
+func goString(s string) string {
+	if strings.ContainsAny(s, "\"\n") && !strings.ContainsAny(s, "`\r") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+func goDuration(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "0"
+	case d%time.Second == 0:
+		return fmt.Sprintf("%d * time.Second", d/time.Second)
+	default:
+		return fmt.Sprintf("%d * time.Millisecond", d/time.Millisecond)
+	}
+}
+
+// splitShell splits a command line like a POSIX shell: single and double
+// quotes, $'...' strings, backslash escapes and line continuations
+func splitShell(s string) ([]string, error) {
+	var (
+		args    []string
+		cur     strings.Builder
+		inToken bool
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inToken {
+				args = append(args, cur.String())
+				cur.Reset()
+				inToken = false
+			}
+		case c == '\\':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				i++
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\r' && i+2 < len(s) && s[i+2] == '\n' {
+				i += 2
+				continue
+			}
+			if i+1 < len(s) {
+				i++
+				cur.WriteByte(s[i])
+			}
+			inToken = true
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("go-requests: unterminated ' in curl command")
+			}
+			cur.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inToken = true
+		case c == '$' && i+1 < len(s) && s[i+1] == '\'':
+			n, err := readANSIQuoted(s[i+2:], &cur)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 2
+			inToken = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				cur.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errors.New(`go-requests: unterminated " in curl command`)
+			}
+			inToken = true
+		default:
+			cur.WriteByte(c)
+			inToken = true
+		}
+	}
+	if inToken {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// readANSIQuoted reads the body of a $'...' string up to the closing quote and
+// returns the number of bytes consumed
+func readANSIQuoted(s string, out *strings.Builder) (int, error) {
+	escapes := map[byte]byte{'n': '\n', 't': '\t', 'r': '\r', '\\': '\\', '\'': '\'', '"': '"', 'a': 7, 'b': 8, 'e': 27, 'f': 12, 'v': 11}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			return i, nil
+		case c == '\\' && i+1 < len(s):
+			i++
+			if e, ok := escapes[s[i]]; ok {
+				out.WriteByte(e)
+			} else if s[i] == 'x' {
+				j := i + 1
+				for j < len(s) && j < i+3 && strings.IndexByte("0123456789abcdefABCDEF", s[j]) >= 0 {
+					j++
+				}
+				v, err := strconv.ParseUint(s[i+1:j], 16, 8)
+				if err != nil {
+					return 0, fmt.Errorf("go-requests: invalid escape in $'...': %s", s[i-1:j])
+				}
+				out.WriteByte(byte(v))
+				i = j - 1
+			} else {
+				out.WriteByte('\\')
+				out.WriteByte(s[i])
+			}
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return 0, errors.New("go-requests: unterminated $' in curl command")
+}
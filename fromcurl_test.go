@@ -0,0 +1,117 @@
+package requests
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitShell(t *testing.T) {
+	args, err := splitShell(`curl -H "X-A: \"q\" \$HOME" \
+  -d 'it'\''s' $'a\tb\x41' plain\ word`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"curl", "-H", `X-A: "q" $HOME`, "-d", "it's", "a\tbA", "plain word"}, args)
+
+	_, err = splitShell(`curl 'unterminated`)
+	assert.NotNil(t, err)
+}
+
+func TestFromCurl(t *testing.T) {
+	c, err := FromCurl(`curl -sSL -XPOST https://api.example.com/v1/items?x=1 \
+  -H 'Content-Type: application/json' -H "Authorization: Bearer token" \
+  -u alice:s3cret -b 'a=1; b=2' --compressed -k --max-time 2.5 --connect-timeout 1 \
+  -d '{"name":"widget"}'`)
+	assert.Nil(t, err)
+	assert.Equal(t, "POST", c.Method)
+	assert.Equal(t, "https://api.example.com/v1/items?x=1", c.URL)
+	assert.Equal(t, `{"name":"widget"}`, c.Params.Data.String())
+	assert.Equal(t, "application/json", c.Params.Headers.Get("Content-Type"))
+	assert.Equal(t, "Bearer token", c.Params.Headers.Get("Authorization"))
+	assert.Equal(t, "a=1; b=2", c.Params.Headers.Get("Cookie"))
+	assert.Equal(t, &Auth{Username: "alice", Password: "s3cret"}, c.Params.Auth)
+	assert.Equal(t, &Timeout{Read: 2500 * time.Millisecond, Connect: time.Second}, c.Params.Timeout)
+	assert.Nil(t, c.Params.AllowRedirects)
+	assert.True(t, c.Insecure)
+
+	c, err = FromCurl(`curl example.com/search -G -d q=go --data-urlencode 'name=a b&c' -I`)
+	assert.Nil(t, err)
+	assert.Equal(t, "HEAD", c.Method)
+	assert.Equal(t, "http://example.com/search?q=go&name=a+b%26c", c.URL)
+	assert.Nil(t, c.Params.Data)
+	assert.False(t, bool(*c.Params.AllowRedirects))
+
+	c, err = FromCurl(`curl https://example.com --json '{"a":1}'`)
+	assert.Nil(t, err)
+	assert.Equal(t, "POST", c.Method)
+	assert.Equal(t, "application/json", c.Params.Headers.Get("Content-Type"))
+	assert.Equal(t, "application/json", c.Params.Headers.Get("Accept"))
+
+	_, err = FromCurl(`curl --unknown https://example.com`)
+	assert.ErrorContains(t, err, "unsupported curl option --unknown")
+	_, err = FromCurl(`curl -H`)
+	assert.NotNil(t, err)
+}
+
+func TestFromCurlSend(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.csv")
+	assert.Nil(t, os.WriteFile(path, []byte("a,b\n"), 0600))
+
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Nil(t, r.ParseMultipartForm(1<<20))
+		f, h, err := r.FormFile("file")
+		assert.Nil(t, err)
+		b, _ := io.ReadAll(f)
+		user, _, _ := r.BasicAuth()
+		w.Write([]byte(strings.Join([]string{r.Method, user, r.FormValue("title"), h.Filename, string(b)}, "|")))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	c, err := FromCurl(`curl -u bob:pw -F title=@literal -F "file=@` + path + `;type=text/csv" ` + ts.URL)
+	assert.NotNil(t, err, "a form value starting with @ is a file for curl")
+
+	c, err = FromCurl(`curl -u bob:pw --form-string title=@literal -F "file=@` + path + `;type=text/csv" ` + ts.URL)
+	assert.Nil(t, err)
+	resp, err := c.Send()
+	assert.Nil(t, err)
+	assert.Equal(t, "POST|bob|@literal|report.csv|a,b\n", resp.Text())
+
+	resp, err = c.Send()
+	assert.Nil(t, err)
+	assert.Equal(t, "POST|bob|@literal|report.csv|a,b\n", resp.Text(), "the request should be sent again")
+
+	src, err := c.GoCode()
+	assert.Nil(t, err)
+	assert.Contains(t, src, `w.WriteField("title", "@literal")`)
+	assert.Contains(t, src, `w.CreateFormFile("file", "report.csv")`)
+	assert.Contains(t, src, "os.ReadFile("+strconv.Quote(path)+")")
+}
+
+func TestFromCurlGoCode(t *testing.T) {
+	c, err := FromCurl(`curl -X PUT https://api.example.com/items/1 -H 'Content-Type: application/json' -d '{"name":"x"}' -u alice:pw -m 10 -k`)
+	assert.Nil(t, err)
+	src, err := c.GoCode()
+	assert.Nil(t, err)
+	assert.Contains(t, src, `"crypto/tls"`)
+	assert.Contains(t, src, "TLSClientConfig: &tls.Config{InsecureSkipVerify: true},")
+	assert.Contains(t, src, "resp, err := s.Put(\"https://api.example.com/items/1\", nil, &requests.RequestParams{")
+	assert.Contains(t, src, "Data: bytes.NewBufferString(`{\"name\":\"x\"}`),")
+	assert.Contains(t, src, `"Content-Type": {"application/json"},`)
+	assert.Contains(t, src, `Auth:           &requests.Auth{Username: "alice", Password: "pw"},`)
+	assert.Contains(t, src, "Timeout:        &requests.Timeout{Connect: 0, Read: 10 * time.Second},")
+	assert.Contains(t, src, "AllowRedirects: requests.Redirect().NotAllow(),")
+
+	c, err = FromCurl(`curl -X PURGE https://example.com/`)
+	assert.Nil(t, err)
+	_, err = c.GoCode()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,165 @@
+package requests
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// histogramSubBits sets the precision of Histogram: values are kept with
+// histogramSubBits+1 significant bits, a relative error below 1%.
+const histogramSubBits = 7
+
+// Histogram records durations in log-linear buckets like HdrHistogram, so
+// quantiles stay within 1% of the recorded values at any scale while using
+// little memory. It is safe for concurrent use.
+type Histogram struct {
+	mu     sync.Mutex
+	counts []int64
+	count  int64
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+// NewHistogram returns an empty Histogram
+func NewHistogram() *Histogram {
+	return &Histogram{}
+}
+
+// histogramIndex is the bucket of a value. Values below 2^(subBits+1) have a
+// bucket each, larger ones share buckets of 2^subBits per power of two.
+func histogramIndex(v int64) int {
+	if v < 1<<(histogramSubBits+1) {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - histogramSubBits - 1
+	return 1<<(histogramSubBits+1) + (shift-1)<<histogramSubBits + int(v>>shift) - 1<<histogramSubBits
+}
+
+// histogramUpper is the largest value of a bucket
+func histogramUpper(i int) int64 {
+	if i < 1<<(histogramSubBits+1) {
+		return int64(i)
+	}
+	i -= 1 << (histogramSubBits + 1)
+	shift := i>>histogramSubBits + 1
+	m := int64(i&(1<<histogramSubBits-1) + 1<<histogramSubBits)
+	return (m+1)<<shift - 1
+}
+
+// Record adds a duration, negative ones count as 0
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	i := histogramIndex(int64(d))
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i >= len(h.counts) {
+		counts := make([]int64, i+1)
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+	h.counts[i]++
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+}
+
+// Merge adds the values recorded by o
+func (h *Histogram) Merge(o *Histogram) {
+	o.mu.Lock()
+	counts := append([]int64(nil), o.counts...)
+	count, sum, min, max := o.count, o.sum, o.min, o.max
+	o.mu.Unlock()
+	if count == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(counts) > len(h.counts) {
+		grown := make([]int64, len(counts))
+		copy(grown, h.counts)
+		h.counts = grown
+	}
+	for i, c := range counts {
+		h.counts[i] += c
+	}
+	if h.count == 0 || min < h.min {
+		h.min = min
+	}
+	if max > h.max {
+		h.max = max
+	}
+	h.count += count
+	h.sum += sum
+}
+
+// Count is the number of recorded values
+func (h *Histogram) Count() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+// Min is the smallest recorded value
+func (h *Histogram) Min() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.min
+}
+
+// Max is the largest recorded value
+func (h *Histogram) Max() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.max
+}
+
+// Mean is the average of the recorded values
+func (h *Histogram) Mean() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.count)
+}
+
+// Quantile returns the value below which the fraction q of the recorded
+// values fall, like 0.99 for p99. It is the largest value of its bucket, so
+// it may be up to 1% more than the values recorded.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.count == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return h.min
+	}
+	rank := int64(math.Ceil(q * float64(h.count)))
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			v := time.Duration(histogramUpper(i))
+			if v > h.max {
+				v = h.max
+			}
+			if v < h.min {
+				v = h.min
+			}
+			return v
+		}
+	}
+	return h.max
+}
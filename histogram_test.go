@@ -0,0 +1,46 @@
+package requests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogramIndex(t *testing.T) {
+	for _, v := range []int64{0, 1, 255, 256, 257, 511, 512, 1000, 123456789, 1 << 40, 1<<62 + 12345} {
+		i := histogramIndex(v)
+		upper := histogramUpper(i)
+		assert.GreaterOrEqual(t, upper, v)
+		assert.LessOrEqual(t, float64(upper-v), float64(v)/128, v)
+		if i > 0 {
+			assert.Less(t, histogramUpper(i-1), v, v)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram()
+	assert.Equal(t, time.Duration(0), h.Quantile(0.5))
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, int64(1000), h.Count())
+	assert.Equal(t, time.Millisecond, h.Min())
+	assert.Equal(t, time.Second, h.Max())
+	assert.Equal(t, 500500*time.Microsecond, h.Mean())
+	for q, want := range map[float64]time.Duration{0.5: 500 * time.Millisecond, 0.9: 900 * time.Millisecond, 0.99: 990 * time.Millisecond, 1: time.Second} {
+		got := h.Quantile(q)
+		assert.InEpsilon(t, float64(want), float64(got), 0.01, q)
+		assert.GreaterOrEqual(t, got, want, q)
+	}
+	assert.Equal(t, time.Millisecond, h.Quantile(0))
+
+	o := NewHistogram()
+	o.Record(time.Hour)
+	h.Merge(o)
+	assert.Equal(t, int64(1001), h.Count())
+	assert.Equal(t, time.Hour, h.Max())
+	assert.Equal(t, time.Hour, h.Quantile(1))
+	assert.Equal(t, time.Millisecond, h.Min())
+}
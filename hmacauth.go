@@ -0,0 +1,135 @@
+package requests
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultHMACMaxAge = 5 * time.Minute
+
+// ErrTimestamp is returned by HMACAuth.Verify for a timestamp outside MaxAge
+var ErrTimestamp = errors.New("go-requests: signature timestamp out of range")
+
+// HMACAuth is an Authenticator for custom HMAC signing schemes, which put an
+// HMAC of the body, optionally with a timestamp, in a header. For example
+// GitHub style webhooks are
+//
+//	&HMACAuth{Key: secret, Header: "X-Hub-Signature-256", Prefix: "sha256="}
+type HMACAuth struct {
+	Key []byte
+	// Header receives the signature, "X-Signature" by default.
+	Header string
+	// Prefix is put in front of the encoded signature.
+	Prefix string
+	// Hash is sha256.New by default.
+	Hash func() hash.Hash
+	// Base64 encodes the signature in base64 instead of hex.
+	Base64 bool
+	// TimestampHeader, when set, receives the unix time and the signed
+	// message becomes "<timestamp>.<body>".
+	TimestampHeader string
+	// MaxAge is how far the timestamp may be from now when verified, against
+	// replayed requests, 5 minutes by default.
+	MaxAge time.Duration
+	// Message builds the signed message instead, when set.
+	Message func(req *http.Request, body []byte) ([]byte, error)
+
+	now func() time.Time
+}
+
+// Authenticate implements Authenticator
+func (a *HMACAuth) Authenticate(req *http.Request) error {
+	body, err := requestBody(req)
+	if err != nil {
+		return err
+	}
+	if a.TimestampHeader != "" {
+		req.Header.Set(a.TimestampHeader, strconv.FormatInt(a.clock().Unix(), 10))
+	}
+	msg, err := a.message(req, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(defaultString(a.Header, "X-Signature"), a.Prefix+a.encode(a.sum(msg)))
+	return nil
+}
+
+// Verify checks the signature of an incoming request, for example in the
+// handler of a webhook. The body is read and replaced with a copy.
+func (a *HMACAuth) Verify(req *http.Request) error {
+	body, err := requestBody(req)
+	if err != nil {
+		return err
+	}
+	got, ok := strings.CutPrefix(req.Header.Get(defaultString(a.Header, "X-Signature")), a.Prefix)
+	if !ok || got == "" {
+		return ErrSignature
+	}
+	msg, err := a.message(req, body)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(got), []byte(a.encode(a.sum(msg)))) {
+		return ErrSignature
+	}
+	if a.TimestampHeader != "" && a.Message == nil {
+		ts, err := strconv.ParseInt(req.Header.Get(a.TimestampHeader), 10, 64)
+		if err != nil {
+			return ErrTimestamp
+		}
+		maxAge := a.MaxAge
+		if maxAge <= 0 {
+			maxAge = defaultHMACMaxAge
+		}
+		if age := a.clock().Sub(time.Unix(ts, 0)); age > maxAge || age < -maxAge {
+			return ErrTimestamp
+		}
+	}
+	return nil
+}
+
+func (a *HMACAuth) clock() time.Time {
+	if a.now != nil {
+		return a.now()
+	}
+	return time.Now()
+}
+
+func (a *HMACAuth) message(req *http.Request, body []byte) ([]byte, error) {
+	if a.Message != nil {
+		return a.Message(req, body)
+	}
+	if a.TimestampHeader != "" {
+		ts := req.Header.Get(a.TimestampHeader)
+		if ts == "" {
+			return nil, errors.New("go-requests: " + a.TimestampHeader + " is missing")
+		}
+		return append([]byte(ts+"."), body...), nil
+	}
+	return body, nil
+}
+
+func (a *HMACAuth) sum(msg []byte) []byte {
+	h := a.Hash
+	if h == nil {
+		h = sha256.New
+	}
+	mac := hmac.New(h, a.Key)
+	mac.Write(msg)
+	return mac.Sum(nil)
+}
+
+func (a *HMACAuth) encode(sum []byte) string {
+	if a.Base64 {
+		return base64.StdEncoding.EncodeToString(sum)
+	}
+	return hex.EncodeToString(sum)
+}
@@ -0,0 +1,60 @@
+package requests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHMACAuth(t *testing.T) {
+	a := &HMACAuth{Key: []byte("It's a Secret to Everybody"), Header: "X-Hub-Signature-256", Prefix: "sha256="}
+
+	// example of the GitHub webhook documentation
+	req, _ := http.NewRequest("POST", "https://example.com/", bytes.NewBufferString("Hello, World!"))
+	assert.Nil(t, a.Authenticate(req))
+	assert.Equal(t, "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", req.Header.Get("X-Hub-Signature-256"))
+	assert.Nil(t, a.Verify(req))
+
+	req.Header.Set("X-Hub-Signature-256", "sha256=00")
+	assert.Equal(t, ErrSignature, a.Verify(req))
+}
+
+func TestHMACAuthTimestamp(t *testing.T) {
+	a := &HMACAuth{Key: []byte("secret"), TimestampHeader: "X-Timestamp", Base64: true}
+
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := a.Verify(r); err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		w.WriteHeader(204)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	s := NewSession()
+	s.Auth = a
+	resp, err := s.Post(ts.URL, nil, &RequestParams{Data: bytes.NewBufferString("payload")})
+	assert.Nil(t, err)
+	assert.Equal(t, 204, resp.StatusCode())
+
+	old := &HMACAuth{Key: []byte("secret"), TimestampHeader: "X-Timestamp", Base64: true, now: func() time.Time { return time.Unix(0, 0) }}
+	req, _ := http.NewRequest("POST", ts.URL, bytes.NewBufferString("payload"))
+	assert.Nil(t, old.Authenticate(req))
+	assert.Equal(t, "0", req.Header.Get("X-Timestamp"))
+	// a replayed request is rejected once it is older than MaxAge
+	assert.Equal(t, ErrTimestamp, a.Verify(req))
+	req.Header.Set("X-Timestamp", "1")
+	assert.Equal(t, ErrSignature, a.Verify(req))
+
+	a.now = func() time.Time { return time.Unix(299, 0) }
+	req, _ = http.NewRequest("POST", ts.URL, bytes.NewBufferString("payload"))
+	assert.Nil(t, old.Authenticate(req))
+	assert.Nil(t, a.Verify(req))
+	a.MaxAge = time.Minute
+	assert.Equal(t, ErrTimestamp, a.Verify(req))
+}
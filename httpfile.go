@@ -0,0 +1,534 @@
+package requests
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPFile is a parsed .http request file, the format of the JetBrains HTTP
+// Client and the VS Code REST Client
+type HTTPFile struct {
+	// Dir is where files referenced with < are read from.
+	Dir string
+	// Variables are the file variables, @name = value.
+	Variables map[string]string
+	Requests  []*HTTPFileRequest
+}
+
+// HTTPFileRequest is a request of an HTTPFile. Its fields are kept as written,
+// {{variables}} are replaced when it is run.
+type HTTPFileRequest struct {
+	// Name is set by "### name" or "# @name name".
+	Name   string
+	Method string
+	URL    string
+	Header http.Header
+	Body   string
+	// NoRedirect is set by "# @no-redirect".
+	NoRedirect bool
+	// Line is where the request starts in the file.
+	Line int
+}
+
+var (
+	httpFileMethods = map[string]bool{
+		http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+		http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+		http.MethodConnect: true, http.MethodTrace: true,
+	}
+	httpFileVariable = regexp.MustCompile(`^@([\w.-]+)\s*=\s*(.*)$`)
+	httpFileTag      = regexp.MustCompile(`^(?:#|//)\s*@([\w-]+)\s*(.*)$`)
+	httpFileTemplate = regexp.MustCompile(`{{\s*(.*?)\s*}}`)
+)
+
+// ParseHTTPFile reads a .http file. Requests are separated by lines starting
+// with ###. Each has a request line, METHOD URL or only the URL for GET,
+// headers and after a blank line the body. A body line "< path" is replaced
+// by the file, relative to the .http file. Response handler scripts, lines
+// starting with >, are not run.
+func ParseHTTPFile(path string) (*HTTPFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	file, err := ParseHTTP(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	file.Dir = filepath.Dir(path)
+	return file, nil
+}
+
+// ParseHTTP parses requests in the .http format, see ParseHTTPFile
+func ParseHTTP(r io.Reader) (*HTTPFile, error) {
+	const (
+		stateStart = iota
+		stateHeaders
+		stateBody
+		stateScript
+	)
+	file := &HTTPFile{Variables: map[string]string{}}
+	var (
+		req        *HTTPFileRequest
+		body       []string
+		state      = stateStart
+		name       string
+		noRedirect bool
+	)
+	finish := func() {
+		if req != nil {
+			// trailing blank lines separate requests, they are not part of the body
+			for len(body) > 0 && strings.TrimSpace(body[len(body)-1]) == "" {
+				body = body[:len(body)-1]
+			}
+			req.Body = strings.Join(body, "\n")
+			file.Requests = append(file.Requests, req)
+		}
+		req, body, state, name, noRedirect = nil, nil, stateStart, "", false
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimRight(sc.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "###") {
+			finish()
+			name = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			continue
+		}
+
+		switch state {
+		case stateStart:
+			if trimmed == "" {
+				continue
+			}
+			if m := httpFileTag.FindStringSubmatch(trimmed); m != nil {
+				switch m[1] {
+				case "name":
+					name = m[2]
+				case "no-redirect":
+					noRedirect = true
+				}
+				continue
+			}
+			if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") {
+				continue
+			}
+			if m := httpFileVariable.FindStringSubmatch(trimmed); m != nil {
+				file.Variables[m[1]] = m[2]
+				continue
+			}
+			req = &HTTPFileRequest{Name: name, Method: http.MethodGet, Header: http.Header{}, NoRedirect: noRedirect, Line: n}
+			fields := strings.Fields(trimmed)
+			if len(fields) > 1 && httpFileMethods[fields[0]] {
+				req.Method, fields = fields[0], fields[1:]
+			}
+			if len(fields) > 1 && strings.HasPrefix(fields[len(fields)-1], "HTTP/") {
+				fields = fields[:len(fields)-1]
+			}
+			req.URL = strings.Join(fields, " ")
+			state = stateHeaders
+		case stateHeaders:
+			switch {
+			case trimmed == "":
+				state = stateBody
+			case (strings.HasPrefix(trimmed, "?") || strings.HasPrefix(trimmed, "&")) && line != trimmed:
+				// an indented query string continues the URL
+				req.URL += trimmed
+			case strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//"):
+			default:
+				k, v, ok := strings.Cut(trimmed, ":")
+				if !ok {
+					return nil, fmt.Errorf("line %d: invalid header %q", n, trimmed)
+				}
+				req.Header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+			}
+		case stateBody:
+			if strings.HasPrefix(line, "> {%") {
+				state = stateScript
+				if strings.HasSuffix(trimmed, "%}") {
+					state = stateBody
+				}
+				continue
+			}
+			if strings.HasPrefix(line, ">") {
+				continue
+			}
+			body = append(body, line)
+		case stateScript:
+			if strings.HasSuffix(trimmed, "%}") {
+				state = stateBody
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	finish()
+	return file, nil
+}
+
+// LoadHTTPEnv reads the variables of environment env from the environment
+// files of the JetBrains HTTP Client in dir, http-client.env.json and
+// http-client.private.env.json, which overrides it. Variables of "$shared"
+// apply to all environments, like in the VS Code REST Client.
+func LoadHTTPEnv(dir, env string) (map[string]string, error) {
+	vars := map[string]string{}
+	found := false
+	for _, name := range []string{"http-client.env.json", "http-client.private.env.json"} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var envs map[string]map[string]interface{}
+		if err := json.Unmarshal(b, &envs); err != nil {
+			return nil, fmt.Errorf("go-requests: %s: %w", name, err)
+		}
+		for _, e := range []string{"$shared", env} {
+			if _, ok := envs[e]; ok && e == env {
+				found = true
+			}
+			for k, v := range envs[e] {
+				if s, ok := v.(string); ok {
+					vars[k] = s
+				} else {
+					b, _ := json.Marshal(v)
+					vars[k] = string(b)
+				}
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("go-requests: environment %q not found in %s", env, dir)
+	}
+	return vars, nil
+}
+
+// HTTPRunner runs the requests of an HTTPFile
+type HTTPRunner struct {
+	// Session sends the requests, the default Session if nil.
+	Session *Session
+	// Env are the environment variables, see LoadHTTPEnv. File variables
+	// take precedence over them.
+	Env map[string]string
+	// Output, if set, gets each request line, response status, headers and body.
+	Output io.Writer
+	// Check is called with each response; an error fails the request.
+	Check func(req *HTTPFileRequest, resp Response) error
+}
+
+// HTTPResult is the outcome of a request run by HTTPRunner
+type HTTPResult struct {
+	Request  *HTTPFileRequest
+	Response Response
+	Err      error
+}
+
+// Run sends the requests of f in order, or only the ones named in names. A
+// response can be used by later requests, like
+// {{login.response.body.$.token}} or {{login.response.headers.Location}} for
+// a request named login. Dynamic variables are {{$uuid}}, {{$timestamp}},
+// {{$isoTimestamp}}, {{$randomInt min max}} and {{$processEnv NAME}}.
+//
+// Failed requests do not stop the run; the returned error joins their errors.
+func (hr *HTTPRunner) Run(f *HTTPFile, names ...string) ([]HTTPResult, error) {
+	s := hr.Session
+	if s == nil {
+		s = defaultSession
+	}
+	only := map[string]bool{}
+	for _, n := range names {
+		only[n] = true
+	}
+	run := &httpRun{runner: hr, file: f, responses: map[string]Response{}}
+
+	var results []HTTPResult
+	var errs []error
+	for _, req := range f.Requests {
+		if len(names) > 0 && !only[req.Name] {
+			continue
+		}
+		resp, err := run.send(s, req)
+		if err == nil && hr.Check != nil {
+			err = hr.Check(req, resp)
+		}
+		if err != nil {
+			label := req.Name
+			if label == "" {
+				label = "line " + strconv.Itoa(req.Line)
+			}
+			err = fmt.Errorf("%s %s (%s): %w", req.Method, req.URL, label, err)
+			errs = append(errs, err)
+		}
+		if req.Name != "" && resp.Raw() != nil {
+			run.responses[req.Name] = resp
+		}
+		results = append(results, HTTPResult{Request: req, Response: resp, Err: err})
+	}
+	return results, errors.Join(errs...)
+}
+
+// httpRun is the state of one HTTPRunner.Run
+type httpRun struct {
+	runner    *HTTPRunner
+	file      *HTTPFile
+	responses map[string]Response
+}
+
+func (r *httpRun) send(s *Session, req *HTTPFileRequest) (Response, error) {
+	urlStr, err := r.expand(req.URL)
+	if err != nil {
+		return Response{}, err
+	}
+	header := http.Header{}
+	for k, vs := range req.Header {
+		for _, v := range vs {
+			if v, err = r.expand(v); err != nil {
+				return Response{}, err
+			}
+			header.Add(k, v)
+		}
+	}
+	if strings.HasPrefix(urlStr, "/") {
+		// the origin is given by the Host header
+		host := header.Get("Host")
+		if host == "" {
+			return Response{}, errors.New("go-requests: relative URL without a Host header")
+		}
+		urlStr = "http://" + host + urlStr
+	} else if !strings.Contains(urlStr, "://") {
+		urlStr = "http://" + urlStr
+	}
+	// like the REST clients, spaces need not be escaped
+	urlStr = strings.ReplaceAll(urlStr, " ", "%20")
+	body, err := r.body(req.Body)
+	if err != nil {
+		return Response{}, err
+	}
+
+	params := &RequestParams{Headers: header, AllowRedirects: Redirect().Allow()}
+	if req.NoRedirect {
+		params.AllowRedirects = Redirect().NotAllow()
+	}
+	if len(body) > 0 {
+		params.Data = bytes.NewBuffer(body)
+	}
+	resp, err := s.send(req.Method, urlStr, nil, params)
+	if err != nil {
+		return Response{}, err
+	}
+	if w := r.runner.Output; w != nil {
+		writeHTTPResult(w, req.Method, urlStr, resp)
+	}
+	return resp, nil
+}
+
+// body expands the variables of the body and reads the files of "< path"
+// lines, which are sent as they are
+func (r *httpRun) body(src string) ([]byte, error) {
+	if src == "" {
+		return nil, nil
+	}
+	var b bytes.Buffer
+	for i, line := range strings.Split(src, "\n") {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		if path, ok := strings.CutPrefix(line, "<@ "); ok {
+			data, err := r.readFile(path)
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.expand(string(data))
+			if err != nil {
+				return nil, err
+			}
+			b.WriteString(s)
+			continue
+		}
+		if path, ok := strings.CutPrefix(line, "< "); ok {
+			data, err := r.readFile(path)
+			if err != nil {
+				return nil, err
+			}
+			b.Write(data)
+			continue
+		}
+		s, err := r.expand(line)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(s)
+	}
+	return b.Bytes(), nil
+}
+
+func (r *httpRun) readFile(path string) ([]byte, error) {
+	path, err := r.expand(strings.TrimSpace(path))
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.file.Dir, path)
+	}
+	return os.ReadFile(path)
+}
+
+// expand replaces the {{variables}} of s
+func (r *httpRun) expand(s string) (string, error) {
+	return r.expandDepth(s, 0)
+}
+
+func (r *httpRun) expandDepth(s string, depth int) (string, error) {
+	if depth > 10 {
+		return "", fmt.Errorf("go-requests: variables nested too deeply in %q", s)
+	}
+	var err error
+	out := httpFileTemplate.ReplaceAllStringFunc(s, func(m string) string {
+		if err != nil {
+			return m
+		}
+		name := httpFileTemplate.FindStringSubmatch(m)[1]
+		var v string
+		v, err = r.variable(name, depth)
+		return v
+	})
+	return out, err
+}
+
+func (r *httpRun) variable(name string, depth int) (string, error) {
+	if strings.HasPrefix(name, "$") {
+		return dynamicVariable(name)
+	}
+	if v, ok := r.file.Variables[name]; ok {
+		return r.expandDepth(v, depth+1)
+	}
+	if v, ok := r.runner.Env[name]; ok {
+		return r.expandDepth(v, depth+1)
+	}
+	if req, ref, ok := strings.Cut(name, ".response."); ok {
+		resp, ok := r.responses[req]
+		if !ok {
+			return "", fmt.Errorf("go-requests: no response of request %q for {{%s}}", req, name)
+		}
+		return responseVariable(resp, ref)
+	}
+	return "", fmt.Errorf("go-requests: undefined variable {{%s}}", name)
+}
+
+// responseVariable resolves body.<JSONPath>, body.* and headers.<name>
+func responseVariable(resp Response, ref string) (string, error) {
+	if h, ok := strings.CutPrefix(ref, "headers."); ok {
+		return resp.Headers().Get(h), nil
+	}
+	path, ok := strings.CutPrefix(ref, "body.")
+	if !ok {
+		return "", fmt.Errorf("go-requests: invalid response reference %q", ref)
+	}
+	if path == "*" || path == "$" {
+		return resp.Text(), nil
+	}
+	// $.data.items[0].id is data.items.0.id for jsonPath
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	v, err := jsonPath(resp.Content(), path)
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	default:
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+}
+
+func dynamicVariable(name string) (string, error) {
+	fields := strings.Fields(name)
+	switch fields[0] {
+	case "$uuid", "$guid", "$random.uuid":
+		return newUUID(), nil
+	case "$timestamp":
+		return strconv.FormatInt(time.Now().Unix(), 10), nil
+	case "$isoTimestamp":
+		return time.Now().UTC().Format(time.RFC3339), nil
+	case "$randomInt", "$random.integer":
+		min, max := int64(0), int64(1000)
+		var err error
+		if len(fields) == 3 {
+			if min, err = strconv.ParseInt(fields[1], 10, 64); err == nil {
+				max, err = strconv.ParseInt(fields[2], 10, 64)
+			}
+		}
+		if err != nil || max <= min {
+			return "", fmt.Errorf("go-requests: invalid {{%s}}", name)
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(max-min))
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(min+n.Int64(), 10), nil
+	case "$processEnv":
+		if len(fields) != 2 {
+			return "", fmt.Errorf("go-requests: invalid {{%s}}", name)
+		}
+		return os.Getenv(fields[1]), nil
+	}
+	return "", fmt.Errorf("go-requests: unknown dynamic variable {{%s}}", name)
+}
+
+// newUUID returns a random UUID, version 4
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func writeHTTPResult(w io.Writer, method, urlStr string, resp Response) {
+	fmt.Fprintf(w, "%s %s\n%s\n", method, urlStr, resp.Status())
+	h := resp.Headers()
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range h[k] {
+			fmt.Fprintf(w, "%s: %s\n", k, v)
+		}
+	}
+	if body := resp.Text(); body != "" {
+		fmt.Fprintf(w, "\n%s", body)
+		if !strings.HasSuffix(body, "\n") {
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,170 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testHTTPFile = `@host = {{scheme}}://{{server}}
+@user = alice
+
+### login
+POST {{host}}/login HTTP/1.1
+Content-Type: application/json
+# a comment
+
+{"user": "{{user}}", "id": "{{$uuid}}"}
+
+> {%
+  client.global.set("token", response.body.token);
+%}
+
+###
+# @name items
+GET {{host}}/items
+    ?page=1
+    &tag=a b
+Authorization: Bearer {{login.response.body.$.token}}
+
+### upload
+# @no-redirect
+PUT {{host}}/upload
+Content-Type: text/plain
+
+< ./data.txt
+`
+
+func TestParseHTTP(t *testing.T) {
+	f, err := ParseHTTP(strings.NewReader(testHTTPFile))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"host": "{{scheme}}://{{server}}", "user": "alice"}, f.Variables)
+	assert.Len(t, f.Requests, 3)
+
+	login := f.Requests[0]
+	assert.Equal(t, "login", login.Name)
+	assert.Equal(t, http.MethodPost, login.Method)
+	assert.Equal(t, "{{host}}/login", login.URL)
+	assert.Equal(t, http.Header{"Content-Type": {"application/json"}}, login.Header)
+	assert.Equal(t, `{"user": "{{user}}", "id": "{{$uuid}}"}`, login.Body)
+	assert.Equal(t, 5, login.Line)
+
+	items := f.Requests[1]
+	assert.Equal(t, "items", items.Name)
+	assert.Equal(t, http.MethodGet, items.Method)
+	assert.Equal(t, "{{host}}/items?page=1&tag=a b", items.URL)
+	assert.Equal(t, "", items.Body)
+
+	upload := f.Requests[2]
+	assert.True(t, upload.NoRedirect)
+	assert.Equal(t, "< ./data.txt", upload.Body)
+
+	_, err = ParseHTTP(strings.NewReader("GET http://x\nbad header\n"))
+	assert.Error(t, err)
+}
+
+func TestLoadHTTPEnv(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "http-client.env.json"), []byte(`{
+		"$shared": {"scheme": "https"},
+		"dev": {"server": "dev.example.com", "port": 8080},
+		"prod": {"server": "example.com"}
+	}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "http-client.private.env.json"), []byte(`{"dev": {"password": "secret"}}`), 0o644)
+
+	env, err := LoadHTTPEnv(dir, "dev")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"scheme": "https", "server": "dev.example.com", "port": "8080", "password": "secret"}, env)
+
+	_, err = LoadHTTPEnv(dir, "staging")
+	assert.Error(t, err)
+}
+
+func TestHTTPRunner(t *testing.T) {
+	var uploaded string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["user"] != "alice" || len(body["id"]) != 36 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"token": "t0k3n"}`))
+		case "/items":
+			if r.Header.Get("Authorization") != "Bearer t0k3n" || r.URL.Query().Get("tag") != "a b" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`[1, 2]`))
+		case "/upload":
+			b, _ := io.ReadAll(r.Body)
+			uploaded = string(b)
+			http.Redirect(w, r, "/done", http.StatusSeeOther)
+		}
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "data.txt"), []byte("file {{user}}"), 0o644)
+	path := filepath.Join(dir, "api.http")
+	os.WriteFile(path, []byte(testHTTPFile), 0o644)
+	f, err := ParseHTTPFile(path)
+	assert.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	runner := &HTTPRunner{
+		Env:    map[string]string{"scheme": "http", "server": strings.TrimPrefix(ts.URL, "http://")},
+		Output: out,
+	}
+	results, err := runner.Run(f)
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, 200, results[0].Response.StatusCode())
+	assert.Equal(t, "[1, 2]", results[1].Response.Text())
+	assert.Equal(t, http.StatusSeeOther, results[2].Response.StatusCode())
+	assert.Equal(t, "file {{user}}", uploaded)
+	assert.Contains(t, out.String(), "GET "+ts.URL+"/items?page=1&tag=a%20b\n200 OK\n")
+
+	// a request using a response which was not run fails
+	runner.Output = nil
+	results, err = runner.Run(f, "items")
+	assert.Len(t, results, 1)
+	assert.ErrorContains(t, err, `no response of request "login"`)
+
+	runner.Check = func(req *HTTPFileRequest, resp Response) error {
+		if resp.StatusCode() != http.StatusOK {
+			return errors.New("unexpected status " + resp.Status())
+		}
+		return nil
+	}
+	results, err = runner.Run(f)
+	assert.Len(t, results, 3)
+	assert.NoError(t, results[0].Err)
+	assert.ErrorContains(t, err, "PUT {{host}}/upload (upload): unexpected status 303 See Other")
+}
+
+func TestHTTPVariables(t *testing.T) {
+	r := &httpRun{runner: &HTTPRunner{Env: map[string]string{"a": "{{b}}", "b": "{{a}}"}}, file: &HTTPFile{}}
+	_, err := r.expand("{{a}}")
+	assert.ErrorContains(t, err, "nested too deeply")
+	_, err = r.expand("{{missing}}")
+	assert.ErrorContains(t, err, "undefined variable {{missing}}")
+
+	v, err := r.expand("{{$randomInt 5 6}}-{{ $processEnv NO_SUCH_VARIABLE }}")
+	assert.NoError(t, err)
+	assert.Equal(t, "5-", v)
+	_, err = r.expand("{{$nope}}")
+	assert.Error(t, err)
+}
@@ -0,0 +1,486 @@
+package requests
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTP message signature algorithms, RFC 9421 3.3
+const (
+	SignatureHMACSHA256   = "hmac-sha256"
+	SignatureEd25519      = "ed25519"
+	SignatureRSAPSSSHA512 = "rsa-pss-sha512"
+)
+
+// ErrSignature is returned when a message signature is missing or does not verify
+var ErrSignature = errors.New("go-requests: invalid message signature")
+
+// HTTPSignature is an Authenticator which signs requests with HTTP Message
+// Signatures, RFC 9421, adding the Signature-Input and Signature headers
+type HTTPSignature struct {
+	// Label names the signature in the headers, "sig1" by default.
+	Label string
+	KeyID string
+	// Algorithm is SignatureHMACSHA256, SignatureEd25519 or SignatureRSAPSSSHA512.
+	Algorithm string
+	// Key is a []byte for HMAC, an ed25519.PrivateKey or an *rsa.PrivateKey.
+	Key interface{}
+	// Components are the covered components, like "@method", "@target-uri" or
+	// a lower case header name. By default "@method", "@target-uri" and, for
+	// requests with a body, "content-digest".
+	Components []string
+	// Expires adds an expiration time to the signature when set.
+	Expires time.Duration
+	// Tag is an application specific tag of the signature.
+	Tag string
+
+	now func() time.Time
+}
+
+// Authenticate implements Authenticator. A Content-Digest header is added when
+// it is covered and the request does not have one yet.
+func (s *HTTPSignature) Authenticate(req *http.Request) error {
+	body, err := requestBody(req)
+	if err != nil {
+		return err
+	}
+	components := s.Components
+	if components == nil {
+		components = []string{"@method", "@target-uri"}
+		if len(body) > 0 {
+			components = append(components, "content-digest")
+		}
+	}
+	for _, c := range components {
+		if c == "content-digest" && req.Header.Get("Content-Digest") == "" {
+			req.Header.Set("Content-Digest", contentDigest(body))
+		}
+	}
+
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	created := now().Unix()
+	params := signatureParams(components, created)
+	if s.Expires > 0 {
+		params += ";expires=" + strconv.FormatInt(created+int64(s.Expires/time.Second), 10)
+	}
+	if s.KeyID != "" {
+		params += ";keyid=" + strconv.Quote(s.KeyID)
+	}
+	if s.Tag != "" {
+		params += ";tag=" + strconv.Quote(s.Tag)
+	}
+
+	base, err := signatureBase(requestMessage(req), components, params)
+	if err != nil {
+		return err
+	}
+	sig, err := signMessage(s.Algorithm, s.Key, []byte(base))
+	if err != nil {
+		return err
+	}
+	label := defaultString(s.Label, "sig1")
+	req.Header.Set("Signature-Input", label+"="+params)
+	req.Header.Set("Signature", label+"=:"+base64.StdEncoding.EncodeToString(sig)+":")
+	return nil
+}
+
+func signatureParams(components []string, created int64) string {
+	quoted := make([]string, len(components))
+	for i, c := range components {
+		quoted[i] = strconv.Quote(strings.ToLower(c))
+	}
+	return "(" + strings.Join(quoted, " ") + ");created=" + strconv.FormatInt(created, 10)
+}
+
+// contentDigest returns a Content-Digest header value, RFC 9530
+func contentDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+}
+
+// message is what the derived components and header fields of a signature are taken from
+type message struct {
+	header http.Header
+	method string
+	url    *url.URL
+	host   string
+	status int
+	length int64
+}
+
+func requestMessage(req *http.Request) message {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	return message{header: req.Header, method: req.Method, url: req.URL, host: host, length: req.ContentLength}
+}
+
+func (m message) component(name string) (string, error) {
+	if !strings.HasPrefix(name, "@") {
+		if vs := m.header.Values(name); len(vs) > 0 {
+			trimmed := make([]string, len(vs))
+			for i, v := range vs {
+				trimmed[i] = strings.TrimSpace(v)
+			}
+			return strings.Join(trimmed, ", "), nil
+		}
+		if name == "content-length" && m.length >= 0 && m.method != "" {
+			return strconv.FormatInt(m.length, 10), nil
+		}
+		return "", fmt.Errorf("go-requests: signed header %q is missing", name)
+	}
+	if name == "@status" {
+		if m.status == 0 {
+			return "", errors.New("go-requests: @status is only defined for responses")
+		}
+		return strconv.Itoa(m.status), nil
+	}
+	if m.url == nil {
+		return "", fmt.Errorf("go-requests: %s is not defined for responses", name)
+	}
+	switch name {
+	case "@method":
+		return strings.ToUpper(m.method), nil
+	case "@target-uri":
+		u := *m.url
+		u.Host = m.host
+		return u.String(), nil
+	case "@authority":
+		return strings.ToLower(m.host), nil
+	case "@scheme":
+		return strings.ToLower(m.url.Scheme), nil
+	case "@request-target":
+		return m.url.RequestURI(), nil
+	case "@path":
+		return defaultString(m.url.EscapedPath(), "/"), nil
+	case "@query":
+		return "?" + m.url.RawQuery, nil
+	}
+	return "", fmt.Errorf("go-requests: unsupported signature component %q", name)
+}
+
+// signatureBase builds the signature base, RFC 9421 2.5
+func signatureBase(m message, components []string, params string) (string, error) {
+	var b strings.Builder
+	for _, c := range components {
+		c = strings.ToLower(c)
+		v, err := m.component(c)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%q: %s\n", c, v)
+	}
+	fmt.Fprintf(&b, "%q: %s", "@signature-params", params)
+	return b.String(), nil
+}
+
+func signMessage(alg string, key interface{}, base []byte) ([]byte, error) {
+	switch alg {
+	case SignatureHMACSHA256:
+		k, ok := key.([]byte)
+		if !ok {
+			return nil, errors.New("go-requests: hmac-sha256 needs a []byte key")
+		}
+		return hmacSHA256(k, base), nil
+	case SignatureEd25519:
+		k, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("go-requests: ed25519 needs an ed25519.PrivateKey")
+		}
+		return ed25519.Sign(k, base), nil
+	case SignatureRSAPSSSHA512:
+		k, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("go-requests: rsa-pss-sha512 needs an *rsa.PrivateKey")
+		}
+		sum := sha512.Sum512(base)
+		return rsa.SignPSS(rand.Reader, k, crypto.SHA512, sum[:], &rsa.PSSOptions{SaltLength: 64})
+	}
+	return nil, fmt.Errorf("go-requests: unsupported signature algorithm %q", alg)
+}
+
+func verifyMessage(alg string, key interface{}, base, sig []byte) error {
+	ok := false
+	switch alg {
+	case SignatureHMACSHA256:
+		k, isKey := key.([]byte)
+		if !isKey {
+			return errors.New("go-requests: hmac-sha256 needs a []byte key")
+		}
+		ok = hmac.Equal(hmacSHA256(k, base), sig)
+	case SignatureEd25519:
+		k, isKey := key.(ed25519.PublicKey)
+		if !isKey {
+			return errors.New("go-requests: ed25519 needs an ed25519.PublicKey")
+		}
+		ok = ed25519.Verify(k, base, sig)
+	case SignatureRSAPSSSHA512:
+		k, isKey := key.(*rsa.PublicKey)
+		if !isKey {
+			return errors.New("go-requests: rsa-pss-sha512 needs an *rsa.PublicKey")
+		}
+		sum := sha512.Sum512(base)
+		ok = rsa.VerifyPSS(k, crypto.SHA512, sum[:], sig, &rsa.PSSOptions{SaltLength: 64}) == nil
+	default:
+		return fmt.Errorf("go-requests: unsupported signature algorithm %q", alg)
+	}
+	if !ok {
+		return ErrSignature
+	}
+	return nil
+}
+
+// SignatureVerifier verifies HTTP Message Signatures, RFC 9421, of responses
+// or of incoming requests such as webhooks
+type SignatureVerifier struct {
+	// Label selects the signature to verify. Empty means the first one,
+	// with KeyID if it is set.
+	Label     string
+	KeyID     string
+	Algorithm string
+	// Key is a []byte for HMAC, an ed25519.PublicKey or an *rsa.PublicKey.
+	Key interface{}
+	// Required components must be covered by the signature.
+	Required []string
+	// MaxAge rejects signatures created longer ago than that, when set.
+	MaxAge time.Duration
+
+	now func() time.Time
+}
+
+// VerifyResponse verifies the signature of resp. A covered Content-Digest is
+// checked against the body, so resp must not be streamed.
+func (v *SignatureVerifier) VerifyResponse(resp Response) error {
+	m := message{header: resp.headers, status: resp.statusCode}
+	if resp.stream != nil && v.covers(resp.headers, "content-digest") {
+		return errors.New("go-requests: cannot verify the Content-Digest of a streamed response")
+	}
+	return v.verify(m, resp.Content())
+}
+
+// VerifyRequest verifies the signature of req, for example in the handler of a
+// webhook. The body is read and replaced with a copy if Content-Digest is covered.
+func (v *SignatureVerifier) VerifyRequest(req *http.Request) error {
+	var body []byte
+	if v.covers(req.Header, "content-digest") {
+		var err error
+		if body, err = requestBody(req); err != nil {
+			return err
+		}
+	}
+	m := requestMessage(req)
+	if m.url.Host == "" {
+		// incoming requests have no scheme and host in their URL
+		u := *req.URL
+		u.Host = m.host
+		u.Scheme = "http"
+		if req.TLS != nil {
+			u.Scheme = "https"
+		}
+		m.url = &u
+	}
+	return v.verify(m, body)
+}
+
+func (v *SignatureVerifier) covers(h http.Header, component string) bool {
+	sig, err := v.find(h)
+	if err != nil {
+		return false
+	}
+	for _, c := range sig.components {
+		if c == component {
+			return true
+		}
+	}
+	return false
+}
+
+type signatureInput struct {
+	label      string
+	params     string
+	components []string
+	created    int64
+	expires    int64
+	keyID      string
+	alg        string
+}
+
+// find returns the Signature-Input member to verify
+func (v *SignatureVerifier) find(h http.Header) (*signatureInput, error) {
+	for _, member := range splitDictionary(strings.Join(h.Values("Signature-Input"), ",")) {
+		in, err := parseSignatureInput(member)
+		if err != nil {
+			return nil, err
+		}
+		if (v.Label == "" || in.label == v.Label) && (v.KeyID == "" || in.keyID == v.KeyID) {
+			return in, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no matching Signature-Input", ErrSignature)
+}
+
+func (v *SignatureVerifier) verify(m message, body []byte) error {
+	in, err := v.find(m.header)
+	if err != nil {
+		return err
+	}
+	if in.alg != "" && in.alg != v.Algorithm {
+		return fmt.Errorf("%w: algorithm %q", ErrSignature, in.alg)
+	}
+	for _, r := range v.Required {
+		found := false
+		for _, c := range in.components {
+			found = found || c == strings.ToLower(r)
+		}
+		if !found {
+			return fmt.Errorf("%w: %q is not covered", ErrSignature, r)
+		}
+	}
+	now := time.Now
+	if v.now != nil {
+		now = v.now
+	}
+	if in.expires != 0 && now().Unix() > in.expires {
+		return fmt.Errorf("%w: expired", ErrSignature)
+	}
+	if v.MaxAge > 0 && (in.created == 0 || now().Sub(time.Unix(in.created, 0)) > v.MaxAge) {
+		return fmt.Errorf("%w: too old", ErrSignature)
+	}
+
+	var sig []byte
+	for _, member := range splitDictionary(strings.Join(m.header.Values("Signature"), ",")) {
+		label, value, _ := strings.Cut(member, "=")
+		if strings.TrimSpace(label) == in.label {
+			value = strings.TrimSpace(value)
+			if len(value) < 2 || value[0] != ':' || value[len(value)-1] != ':' {
+				return fmt.Errorf("%w: malformed Signature", ErrSignature)
+			}
+			if sig, err = base64.StdEncoding.DecodeString(value[1 : len(value)-1]); err != nil {
+				return fmt.Errorf("%w: malformed Signature", ErrSignature)
+			}
+		}
+	}
+	if sig == nil {
+		return fmt.Errorf("%w: no Signature %q", ErrSignature, in.label)
+	}
+
+	base, err := signatureBase(m, in.components, in.params)
+	if err != nil {
+		return err
+	}
+	if err := verifyMessage(v.Algorithm, v.Key, []byte(base), sig); err != nil {
+		return err
+	}
+	for _, c := range in.components {
+		if c == "content-digest" {
+			return verifyContentDigest(m.header.Get("Content-Digest"), body)
+		}
+	}
+	return nil
+}
+
+// verifyContentDigest checks the sha-256 or sha-512 digests of a Content-Digest header
+func verifyContentDigest(header string, body []byte) error {
+	checked := false
+	for _, member := range splitDictionary(header) {
+		alg, value, _ := strings.Cut(member, "=")
+		value = strings.Trim(strings.TrimSpace(value), ":")
+		var sum []byte
+		switch strings.TrimSpace(alg) {
+		case "sha-256":
+			s := sha256.Sum256(body)
+			sum = s[:]
+		case "sha-512":
+			s := sha512.Sum512(body)
+			sum = s[:]
+		default:
+			continue
+		}
+		if base64.StdEncoding.EncodeToString(sum) != value {
+			return fmt.Errorf("%w: Content-Digest does not match the body", ErrSignature)
+		}
+		checked = true
+	}
+	if !checked {
+		return fmt.Errorf("%w: no supported Content-Digest", ErrSignature)
+	}
+	return nil
+}
+
+// splitDictionary splits a structured field dictionary, RFC 8941, into its
+// members, keeping commas inside strings and inner lists
+func splitDictionary(s string) []string {
+	var members []string
+	depth, quoted, start := 0, false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			members = append(members, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		members = append(members, last)
+	}
+	return members
+}
+
+func parseSignatureInput(member string) (*signatureInput, error) {
+	label, value, ok := strings.Cut(member, "=")
+	value = strings.TrimSpace(value)
+	end := strings.IndexByte(value, ')')
+	if !ok || !strings.HasPrefix(value, "(") || end < 0 {
+		return nil, fmt.Errorf("%w: malformed Signature-Input", ErrSignature)
+	}
+	in := &signatureInput{label: strings.TrimSpace(label), params: value}
+	for _, c := range strings.Fields(value[1:end]) {
+		name, err := strconv.Unquote(c)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unsupported component %s", ErrSignature, c)
+		}
+		in.components = append(in.components, name)
+	}
+	for _, p := range strings.Split(value[end+1:], ";") {
+		k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+		if uq, err := strconv.Unquote(v); err == nil {
+			v = uq
+		}
+		switch k {
+		case "created":
+			in.created, _ = strconv.ParseInt(v, 10, 64)
+		case "expires":
+			in.expires, _ = strconv.ParseInt(v, 10, 64)
+		case "keyid":
+			in.keyID = v
+		case "alg":
+			in.alg = v
+		}
+	}
+	return in, nil
+}
@@ -0,0 +1,127 @@
+package requests
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the example request of RFC 9421 B.2
+func rfc9421Request() *http.Request {
+	req, _ := http.NewRequest("POST", "https://example.com/foo?param=Value&Pet=dog", strings.NewReader(`{"hello": "world"}`))
+	req.Header.Set("Date", "Tue, 20 Apr 2021 02:07:55 GMT")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Digest", "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:")
+	return req
+}
+
+func TestHTTPSignatureHMACVector(t *testing.T) {
+	key, _ := base64.StdEncoding.DecodeString("uzvJfB4u3N0Jy4T7NZ75MDVcr8zSTInedJtkgcu46YW4XByzNJjxBdtjUkdJPBtbmHhIDi6pcl8jsasjlTMtDQ==")
+	s := &HTTPSignature{
+		Label:      "sig-b25",
+		KeyID:      "test-shared-secret",
+		Algorithm:  SignatureHMACSHA256,
+		Key:        key,
+		Components: []string{"date", "@authority", "content-type"},
+		now:        func() time.Time { return time.Unix(1618884473, 0) },
+	}
+	req := rfc9421Request()
+	assert.Nil(t, s.Authenticate(req))
+	assert.Equal(t, `sig-b25=("date" "@authority" "content-type");created=1618884473;keyid="test-shared-secret"`, req.Header.Get("Signature-Input"))
+	assert.Equal(t, "sig-b25=:pxcQw6G3AjtMBQjwo8XzkZf/bws5LelbaMk5rGIGtE8=:", req.Header.Get("Signature"))
+
+	v := &SignatureVerifier{KeyID: "test-shared-secret", Algorithm: SignatureHMACSHA256, Key: key}
+	assert.Nil(t, v.VerifyRequest(req))
+	req.Header.Set("Content-Type", "text/plain")
+	assert.ErrorIs(t, v.VerifyRequest(req), ErrSignature)
+}
+
+func TestHTTPSignatureEd25519Vector(t *testing.T) {
+	seed, _ := base64.RawURLEncoding.DecodeString("n4Ni-HpISpVObnQMW0wOhCKROaIKqKtW_2ZYb2p9KcU")
+	key := ed25519.NewKeyFromSeed(seed)
+	s := &HTTPSignature{
+		Label:      "sig-b26",
+		KeyID:      "test-key-ed25519",
+		Algorithm:  SignatureEd25519,
+		Key:        key,
+		Components: []string{"date", "@method", "@path", "@authority", "content-type", "content-length"},
+		now:        func() time.Time { return time.Unix(1618884473, 0) },
+	}
+	req := rfc9421Request()
+	assert.Nil(t, s.Authenticate(req))
+	assert.Equal(t, "sig-b26=:wqcAqbmYJ2ji2glfAMaRy4gruYYnx2nEFN2HN6jrnDnQCK1u02Gb04v9EDgwUPiu4A0w6vuQv5lIp5WPpBKRCw==:", req.Header.Get("Signature"))
+
+	v := &SignatureVerifier{Algorithm: SignatureEd25519, Key: key.Public(), Required: []string{"@method", "@path"}}
+	assert.Nil(t, v.VerifyRequest(req))
+	v.Required = []string{"content-digest"}
+	assert.ErrorIs(t, v.VerifyRequest(req), ErrSignature)
+}
+
+func TestHTTPSignatureRSAPSS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := &SignatureVerifier{KeyID: "client", Algorithm: SignatureRSAPSSSHA512, Key: &key.PublicKey, MaxAge: time.Minute}
+		if err := v.VerifyRequest(r); err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		body := []byte(`{"ok":true}`)
+		w.Header().Set("Content-Digest", contentDigest(body))
+		params := signatureParams([]string{"@status", "content-digest"}, time.Now().Unix()) + `;keyid="server"`
+		base, _ := signatureBase(message{header: w.Header(), status: 200}, []string{"@status", "content-digest"}, params)
+		sig, _ := signMessage(SignatureRSAPSSSHA512, key, []byte(base))
+		w.Header().Set("Signature-Input", "res="+params)
+		w.Header().Set("Signature", "res=:"+base64.StdEncoding.EncodeToString(sig)+":")
+		w.Write(body)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	s := NewSession()
+	s.Auth = &HTTPSignature{KeyID: "client", Algorithm: SignatureRSAPSSSHA512, Key: key, Expires: time.Minute}
+	resp, err := s.Post(ts.URL+"/webhook", nil, &RequestParams{Json: map[string]string{"event": "ping"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode())
+
+	v := &SignatureVerifier{Label: "res", Algorithm: SignatureRSAPSSSHA512, Key: &key.PublicKey, Required: []string{"@status", "content-digest"}}
+	assert.Nil(t, v.VerifyResponse(resp))
+
+	tampered := resp
+	tampered.body.Reset()
+	tampered.body.WriteString(`{"ok":false}`)
+	assert.ErrorIs(t, v.VerifyResponse(tampered), ErrSignature)
+}
+
+func TestHTTPSignatureExpired(t *testing.T) {
+	key := []byte("secret")
+	s := &HTTPSignature{Algorithm: SignatureHMACSHA256, Key: key, Expires: time.Minute, now: func() time.Time { return time.Now().Add(-time.Hour) }}
+	req, _ := http.NewRequest("GET", "https://example.com/", nil)
+	assert.Nil(t, s.Authenticate(req))
+	assert.Equal(t, []string{"@method", "@target-uri"}, mustParseInput(t, req.Header.Get("Signature-Input")).components)
+
+	v := &SignatureVerifier{Algorithm: SignatureHMACSHA256, Key: key}
+	assert.ErrorIs(t, v.VerifyRequest(req), ErrSignature)
+	v.now = func() time.Time { return time.Now().Add(-time.Hour) }
+	assert.Nil(t, v.VerifyRequest(req))
+}
+
+func mustParseInput(t *testing.T, s string) *signatureInput {
+	in, err := parseSignatureInput(s)
+	assert.Nil(t, err)
+	return in
+}
+
+func TestSplitDictionary(t *testing.T) {
+	assert.Equal(t, []string{`a=("x" "y");keyid="k,1"`, `b=:YWJj:`}, splitDictionary(`a=("x" "y");keyid="k,1", b=:YWJj:`))
+	assert.Empty(t, splitDictionary(""))
+}
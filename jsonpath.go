@@ -0,0 +1,37 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// jsonPath returns the value at a dotted path like "data.items.0.id" in a JSON
+// document. Numbers are returned as json.Number. An empty path returns the whole document.
+func jsonPath(body []byte, path string) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return v, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			v = node[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, nil
+			}
+			v = node[i]
+		default:
+			return nil, nil
+		}
+	}
+	return v, nil
+}
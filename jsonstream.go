@@ -0,0 +1,164 @@
+//go:build go1.23
+
+package requests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"strings"
+)
+
+// RecordError is returned for a record which could not be decoded. The
+// stream can be read on after it.
+type RecordError struct {
+	Index int
+	Err   error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("go-requests: record %d: %v", e.Index, e.Err)
+}
+
+func (e *RecordError) Unwrap() error { return e.Err }
+
+// JSONStream decodes JSON records one at a time from a response body
+type JSONStream struct {
+	ctx   context.Context
+	body  io.ReadCloser
+	br    *bufio.Reader
+	dec   *json.Decoder // set for a top-level JSON array
+	index int
+	err   error
+	stop  func() bool
+}
+
+// DecodeStream decodes the body as newline-delimited JSON, or as the elements
+// of a top-level JSON array when it starts with "[" and the Content-Type is
+// not application/x-ndjson. Use it with RequestParams.Stream to avoid
+// buffering the body. When ctx is done the body is closed.
+func (resp Response) DecodeStream(ctx context.Context) *JSONStream {
+	body := resp.Body()
+	s := &JSONStream{
+		ctx:  ctx,
+		body: body,
+		br:   bufio.NewReader(body),
+		stop: context.AfterFunc(ctx, func() { body.Close() }),
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Headers().Get("Content-Type"))
+	switch mediaType {
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return s
+	}
+	for {
+		b, err := s.br.Peek(1)
+		if err != nil {
+			return s
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b[0])) {
+			break
+		}
+		s.br.ReadByte()
+	}
+	if b, _ := s.br.Peek(1); b[0] == '[' {
+		s.dec = json.NewDecoder(s.br)
+		s.dec.Token()
+	}
+	return s
+}
+
+// Next decodes the next record into dst. It returns io.EOF after the last
+// record, and a *RecordError for a record which does not fit dst or, in
+// NDJSON, is malformed.
+func (s *JSONStream) Next(dst interface{}) error {
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.ctx.Err(); err != nil {
+		return s.fail(err)
+	}
+	if s.dec != nil {
+		return s.nextElement(dst)
+	}
+	for {
+		line, err := s.br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err != nil {
+				return s.fail(err)
+			}
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return s.fail(err)
+		}
+		s.index++
+		if err := json.Unmarshal(line, dst); err != nil {
+			return &RecordError{Index: s.index - 1, Err: err}
+		}
+		return nil
+	}
+}
+
+func (s *JSONStream) nextElement(dst interface{}) error {
+	if !s.dec.More() {
+		if _, err := s.dec.Token(); err != nil {
+			return s.fail(err)
+		}
+		return s.fail(io.EOF)
+	}
+	var raw json.RawMessage
+	if err := s.dec.Decode(&raw); err != nil {
+		// the array is broken, so the rest of it can not be read
+		return s.fail(err)
+	}
+	s.index++
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return &RecordError{Index: s.index - 1, Err: err}
+	}
+	return nil
+}
+
+func (s *JSONStream) fail(err error) error {
+	if ctxErr := s.ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+	s.err = err
+	s.Close()
+	return err
+}
+
+// Close closes the response body
+func (s *JSONStream) Close() error {
+	s.stop()
+	return s.body.Close()
+}
+
+// DecodeStream iterates over the records of resp decoded as T. A *RecordError
+// is yielded for a bad record and the iteration goes on; any other error ends it.
+//
+//	for v, err := range requests.DecodeStream[LogLine](ctx, resp) { ... }
+func DecodeStream[T any](ctx context.Context, resp Response) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		s := resp.DecodeStream(ctx)
+		defer s.Close()
+		for {
+			var v T
+			err := s.Next(&v)
+			if err == io.EOF {
+				return
+			}
+			if !yield(v, err) {
+				return
+			}
+			if _, ok := err.(*RecordError); err != nil && !ok {
+				return
+			}
+		}
+	}
+}
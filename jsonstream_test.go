@@ -0,0 +1,103 @@
+//go:build go1.23
+
+package requests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type logLine struct {
+	Level string `json:"level"`
+	N     int    `json:"n"`
+}
+
+func TestDecodeStreamNDJSON(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprint(w, `{"level":"info","n":1}`+"\n\n")
+		fmt.Fprint(w, `{"level":"warn","n":"two"}`+"\n")
+		fmt.Fprint(w, "not json\n")
+		fmt.Fprint(w, `{"level":"error","n":4}`)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	resp, err := Get(ts.URL, nil, &RequestParams{Stream: true})
+	assert.Nil(t, err)
+
+	var (
+		lines []logLine
+		bad   []int
+	)
+	for v, err := range DecodeStream[logLine](context.Background(), resp) {
+		var re *RecordError
+		if errors.As(err, &re) {
+			bad = append(bad, re.Index)
+			continue
+		}
+		assert.Nil(t, err)
+		lines = append(lines, v)
+	}
+	assert.Equal(t, []logLine{{"info", 1}, {"error", 4}}, lines)
+	assert.Equal(t, []int{1, 2}, bad)
+}
+
+func TestDecodeStreamArray(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, ` [{"level":"info","n":1}, {"level":"debug","n":2}, {"n":"x"}, {"n":3}]`)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	resp, err := Get(ts.URL, nil, nil)
+	assert.Nil(t, err)
+
+	s := resp.DecodeStream(context.Background())
+	defer s.Close()
+	var v logLine
+	assert.Nil(t, s.Next(&v))
+	assert.Equal(t, logLine{"info", 1}, v)
+	assert.Nil(t, s.Next(&v))
+	assert.Equal(t, logLine{"debug", 2}, v)
+	_, ok := s.Next(&v).(*RecordError)
+	assert.True(t, ok)
+	v = logLine{}
+	assert.Nil(t, s.Next(&v))
+	assert.Equal(t, 3, v.N)
+	assert.Equal(t, io.EOF, s.Next(&v))
+}
+
+func TestDecodeStreamCancel(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"n":1}`+"\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	resp, err := Get(ts.URL, nil, &RequestParams{Stream: true})
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var errs []error
+	for v, err := range DecodeStream[logLine](ctx, resp) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		assert.Equal(t, 1, v.N)
+		cancel()
+	}
+	assert.Equal(t, []error{context.Canceled}, errs)
+}
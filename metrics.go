@@ -0,0 +1,250 @@
+package requests
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMetricsBuckets are the upper bounds of the latency histogram in
+// seconds, the default buckets of the Prometheus client libraries
+var DefaultMetricsBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics collects metrics of the requests sent by a Session and serves them
+// in the Prometheus text format. Every request sent is counted, including
+// redirects and retries. The zero Metrics is ready to use.
+//
+//	http_client_requests_total{method,host,status_class}  counter, status_class is 2xx..5xx or error
+//	http_client_request_duration_seconds{method,host}      histogram, until the body was read
+//	http_client_requests_in_flight{host}                   gauge
+//	http_client_retries_total{method,host}                 counter, retries after 401 Unauthorized
+//	http_client_request_body_bytes_total{method,host}      counter
+//	http_client_response_body_bytes_total{method,host}     counter
+type Metrics struct {
+	// Namespace, if set, prefixes the metric names, like "myapp_http_client_requests_total".
+	Namespace string
+	// Buckets are the upper bounds of the latency histogram in seconds,
+	// DefaultMetricsBuckets if nil. Set them before the first request.
+	Buckets []float64
+
+	mu            sync.Mutex
+	requests      map[[3]string]uint64
+	durations     map[[2]string]*metricsHistogram
+	inFlight      map[string]int64
+	retries       map[[2]string]uint64
+	bytesSent     map[[2]string]uint64
+	bytesReceived map[[2]string]uint64
+}
+
+type metricsHistogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewMetrics returns an empty Metrics. Assign it to Session.Metrics.
+func NewMetrics() *Metrics {
+	m := &Metrics{}
+	m.init()
+	return m
+}
+
+// init makes the zero Metrics usable, with m.mu held
+func (m *Metrics) init() {
+	if m.requests != nil {
+		return
+	}
+	m.requests = map[[3]string]uint64{}
+	m.durations = map[[2]string]*metricsHistogram{}
+	m.inFlight = map[string]int64{}
+	m.retries = map[[2]string]uint64{}
+	m.bytesSent = map[[2]string]uint64{}
+	m.bytesReceived = map[[2]string]uint64{}
+}
+
+func metricsHost(u *url.URL) string {
+	if socket, ok := unixSocket(u.Host); ok {
+		return socket
+	}
+	return u.Host
+}
+
+func (m *Metrics) buckets() []float64 {
+	if m.Buckets == nil {
+		return DefaultMetricsBuckets
+	}
+	return m.Buckets
+}
+
+// start counts a request in flight
+func (m *Metrics) start(req *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.inFlight[metricsHost(req.URL)]++
+}
+
+// observe records a request started with start
+func (m *Metrics) observe(req *http.Request, resp *http.Response, err error, d time.Duration, received int64) {
+	host := metricsHost(req.URL)
+	key := [2]string{req.Method, host}
+	class := "error"
+	if err == nil && resp != nil {
+		class = strconv.Itoa(resp.StatusCode/100) + "xx"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.inFlight[host]--
+	m.requests[[3]string{req.Method, host, class}]++
+	if req.ContentLength > 0 {
+		m.bytesSent[key] += uint64(req.ContentLength)
+	}
+	m.bytesReceived[key] += uint64(received)
+	if err != nil {
+		return
+	}
+	h := m.durations[key]
+	if h == nil {
+		h = &metricsHistogram{counts: make([]uint64, len(m.buckets()))}
+		m.durations[key] = h
+	}
+	s := d.Seconds()
+	for i, le := range m.buckets() {
+		if s <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += s
+}
+
+func (m *Metrics) retry(req *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.retries[[2]string{req.Method, metricsHost(req.URL)}]++
+}
+
+func (m *Metrics) received(req *http.Request, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.bytesReceived[[2]string{req.Method, metricsHost(req.URL)}] += uint64(n)
+}
+
+// meteredBody counts the bytes of a streamed body as they are read
+type meteredBody struct {
+	io.ReadCloser
+	metrics *Metrics
+	req     *http.Request
+}
+
+func (b *meteredBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.metrics.received(b.req, n)
+	}
+	return n, err
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	name := func(n string) string {
+		if m.Namespace != "" {
+			return m.Namespace + "_" + n
+		}
+		return n
+	}
+	header := func(n, typ, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", n, help, n, typ)
+	}
+
+	m.mu.Lock()
+	m.init()
+	n := name("http_client_requests_total")
+	header(n, "counter", "Requests sent, by status class.")
+	for _, k := range sortedKeys(m.requests) {
+		fmt.Fprintf(&b, "%s{method=%s,host=%s,status_class=%s} %d\n", n, labelValue(k[0]), labelValue(k[1]), labelValue(k[2]), m.requests[k])
+	}
+
+	n = name("http_client_request_duration_seconds")
+	header(n, "histogram", "Time from sending a request until its response body was read.")
+	for _, k := range sortedKeys(m.durations) {
+		h := m.durations[k]
+		labels := fmt.Sprintf("method=%s,host=%s", labelValue(k[0]), labelValue(k[1]))
+		var cumulative uint64
+		for i, le := range m.buckets() {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&b, "%s_bucket{%s,le=\"%s\"} %d\n", n, labels, formatFloat(le), cumulative)
+		}
+		fmt.Fprintf(&b, "%s_bucket{%s,le=\"+Inf\"} %d\n", n, labels, h.count)
+		fmt.Fprintf(&b, "%s_sum{%s} %s\n", n, labels, formatFloat(h.sum))
+		fmt.Fprintf(&b, "%s_count{%s} %d\n", n, labels, h.count)
+	}
+
+	n = name("http_client_requests_in_flight")
+	header(n, "gauge", "Requests waiting for their response.")
+	for _, k := range sortedKeys(m.inFlight) {
+		fmt.Fprintf(&b, "%s{host=%s} %d\n", n, labelValue(k), m.inFlight[k])
+	}
+
+	for _, c := range []struct {
+		name, help string
+		values     map[[2]string]uint64
+	}{
+		{"http_client_retries_total", "Requests sent again after 401 Unauthorized.", m.retries},
+		{"http_client_request_body_bytes_total", "Bytes of request bodies sent.", m.bytesSent},
+		{"http_client_response_body_bytes_total", "Bytes of response bodies read.", m.bytesReceived},
+	} {
+		n = name(c.name)
+		header(n, "counter", c.help)
+		for _, k := range sortedKeys(c.values) {
+			fmt.Fprintf(&b, "%s{method=%s,host=%s} %d\n", n, labelValue(k[0]), labelValue(k[1]), c.values[k])
+		}
+	}
+	m.mu.Unlock()
+
+	return b.WriteTo(w)
+}
+
+// sortedKeys sorts label values for a stable output
+func sortedKeys[K [2]string | [3]string | string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+	return keys
+}
+
+// labelValue quotes a label value of the text format
+func labelValue(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,146 @@
+package requests
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/auth", http.StatusFound)
+		case "/auth":
+			attempts++
+			if attempts == 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			io.WriteString(w, "hello")
+		case "/missing":
+			http.NotFound(w, r)
+		case "/post":
+			io.Copy(io.Discard, r.Body)
+			io.WriteString(w, "ok")
+		}
+	}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	m := NewMetrics()
+	s := NewSession()
+	defer s.Close()
+	s.Metrics = m
+	s.Auth = &retryingAuth{}
+	_, err := s.Get(ts.URL+"/redirect", nil, &RequestParams{AllowRedirects: Redirect().Allow()})
+	assert.NoError(t, err)
+	s.Auth = nil
+	_, err = s.Get(ts.URL+"/missing", nil, nil)
+	assert.NoError(t, err)
+	_, err = s.Post(ts.URL+"/post", nil, &RequestParams{Data: bytes.NewBufferString("a=b")})
+	assert.NoError(t, err)
+
+	var b bytes.Buffer
+	_, err = m.WriteTo(&b)
+	assert.NoError(t, err)
+	out := b.String()
+	label := `method="GET",host="` + host + `"`
+	for _, line := range []string{
+		"# TYPE http_client_requests_total counter",
+		`http_client_requests_total{` + label + `,status_class="2xx"} 1`,
+		`http_client_requests_total{` + label + `,status_class="3xx"} 1`,
+		`http_client_requests_total{` + label + `,status_class="4xx"} 2`,
+		`http_client_requests_total{method="POST",host="` + host + `",status_class="2xx"} 1`,
+		"# TYPE http_client_request_duration_seconds histogram",
+		`http_client_request_duration_seconds_bucket{` + label + `,le="+Inf"} 4`,
+		`http_client_request_duration_seconds_count{` + label + `} 4`,
+		`http_client_requests_in_flight{host="` + host + `"} 0`,
+		`http_client_retries_total{` + label + `} 1`,
+		`http_client_request_body_bytes_total{method="POST",host="` + host + `"} 3`,
+		`http_client_response_body_bytes_total{` + label + `} 24`,
+		`http_client_response_body_bytes_total{method="POST",host="` + host + `"} 2`,
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+	assert.Contains(t, out, `http_client_request_duration_seconds_bucket{`+label+`,le="10"} 4`)
+}
+
+func TestMetricsError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Close()
+
+	m := &Metrics{Namespace: "myapp"}
+	s := NewSession()
+	defer s.Close()
+	s.Metrics = m
+	_, err := s.Get(ts.URL, nil, nil)
+	assert.Error(t, err)
+
+	var b bytes.Buffer
+	m.WriteTo(&b)
+	host := strings.TrimPrefix(ts.URL, "http://")
+	assert.Contains(t, b.String(), `myapp_http_client_requests_total{method="GET",host="`+host+`",status_class="error"} 1`+"\n")
+	assert.Contains(t, b.String(), `myapp_http_client_requests_in_flight{host="`+host+`"} 0`+"\n")
+	// failed requests have no latency
+	assert.NotContains(t, b.String(), "myapp_http_client_request_duration_seconds_count")
+}
+
+func TestMetricsStream(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "streamed")
+	}))
+	defer ts.Close()
+
+	m := NewMetrics()
+	s := NewSession()
+	defer s.Close()
+	s.Metrics = m
+	resp, err := s.Get(ts.URL, nil, &RequestParams{Stream: true})
+	assert.NoError(t, err)
+	body := resp.Body()
+	io.Copy(io.Discard, body)
+	body.Close()
+
+	var b bytes.Buffer
+	m.WriteTo(&b)
+	host := strings.TrimPrefix(ts.URL, "http://")
+	assert.Contains(t, b.String(), `http_client_response_body_bytes_total{method="GET",host="`+host+`"} 8`+"\n")
+}
+
+func TestMetricsBuckets(t *testing.T) {
+	m := &Metrics{Buckets: []float64{0.25, 1}}
+	req := &http.Request{Method: "GET", URL: &url.URL{Host: "example.com"}}
+	for _, d := range []float64{0.25, 0.5, 4} {
+		m.start(req)
+		m.observe(req, &http.Response{StatusCode: 200}, nil, time.Duration(d*float64(time.Second)), 0)
+	}
+
+	var b bytes.Buffer
+	m.WriteTo(&b)
+	assert.Contains(t, b.String(), `http_client_request_duration_seconds_bucket{method="GET",host="example.com",le="0.25"} 1
+http_client_request_duration_seconds_bucket{method="GET",host="example.com",le="1"} 2
+http_client_request_duration_seconds_bucket{method="GET",host="example.com",le="+Inf"} 3
+http_client_request_duration_seconds_sum{method="GET",host="example.com"} 4.75
+http_client_request_duration_seconds_count{method="GET",host="example.com"} 3
+`)
+}
+
+func TestMetricsServeHTTP(t *testing.T) {
+	m := NewMetrics()
+	req := &http.Request{Method: "GET", URL: &url.URL{Host: "a\"b\\c"}}
+	m.start(req)
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `http_client_requests_in_flight{host="a\"b\\c"} 1`+"\n")
+}
@@ -0,0 +1,137 @@
+package requests
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+type netrcEntry struct {
+	machine  string // empty for the default entry
+	login    string
+	password string
+}
+
+// netrcCache keeps the parsed .netrc until the file changes
+var netrcCache struct {
+	sync.Mutex
+	path    string
+	modTime time.Time
+	size    int64
+	entries []netrcEntry
+}
+
+// netrcPath returns $NETRC, or .netrc (_netrc on Windows) in the home directory
+func netrcPath() string {
+	if p := os.Getenv("NETRC"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	if runtime.GOOS == "windows" {
+		if p := filepath.Join(home, "_netrc"); fileExists(p) {
+			return p
+		}
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// netrcAuth returns the credentials for host in the .netrc file, or nil
+func netrcAuth(host string) *Auth {
+	path := netrcPath()
+	if path == "" {
+		return nil
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+
+	netrcCache.Lock()
+	if netrcCache.path != path || !netrcCache.modTime.Equal(fi.ModTime()) || netrcCache.size != fi.Size() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			netrcCache.Unlock()
+			return nil
+		}
+		netrcCache.path, netrcCache.modTime, netrcCache.size = path, fi.ModTime(), fi.Size()
+		netrcCache.entries = parseNetrc(string(data))
+	}
+	entries := netrcCache.entries
+	netrcCache.Unlock()
+
+	var def *netrcEntry
+	for i, e := range entries {
+		if e.machine == "" {
+			if def == nil {
+				def = &entries[i]
+			}
+		} else if strings.EqualFold(e.machine, host) {
+			return &Auth{Username: e.login, Password: e.password}
+		}
+	}
+	if def != nil {
+		return &Auth{Username: def.login, Password: def.password}
+	}
+	return nil
+}
+
+// parseNetrc parses the machine and default entries of a .netrc file. Macro
+// definitions are skipped.
+func parseNetrc(data string) []netrcEntry {
+	var (
+		entries []netrcEntry
+		current *netrcEntry
+	)
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		for j := 0; j < len(fields); j++ {
+			if strings.HasPrefix(fields[j], "#") {
+				break
+			}
+			next := func() string {
+				if j+1 < len(fields) {
+					j++
+					return fields[j]
+				}
+				return ""
+			}
+			switch fields[j] {
+			case "machine":
+				entries = append(entries, netrcEntry{machine: next()})
+				current = &entries[len(entries)-1]
+			case "default":
+				entries = append(entries, netrcEntry{})
+				current = &entries[len(entries)-1]
+			case "login":
+				if v := next(); current != nil {
+					current.login = v
+				}
+			case "password":
+				if v := next(); current != nil {
+					current.password = v
+				}
+			case "account":
+				next()
+			case "macdef":
+				// the macro runs until an empty line
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				j = len(fields)
+			}
+		}
+	}
+	return entries
+}
@@ -0,0 +1,84 @@
+package requests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNetrc(t *testing.T) {
+	entries := parseNetrc(`# comment
+machine example.com login alice password s3cret
+machine api.example.com
+	login bob
+	account ignored
+	password pw # trailing comment
+
+macdef init
+machine evil.example.com login mallory password x
+
+default login anonymous password guest
+`)
+	assert.Equal(t, []netrcEntry{
+		{machine: "example.com", login: "alice", password: "s3cret"},
+		{machine: "api.example.com", login: "bob", password: "pw"},
+		{login: "anonymous", password: "guest"},
+	}, entries)
+}
+
+func writeNetrc(t *testing.T, content string) {
+	path := filepath.Join(t.TempDir(), "netrc")
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0600))
+	t.Setenv("NETRC", path)
+}
+
+func TestNetrc(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		w.Write([]byte(user + ":" + pass))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	writeNetrc(t, "machine 127.0.0.1 login alice password s3cret\n")
+
+	s := NewSession()
+	resp, err := s.Get(ts.URL, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice:s3cret", resp.Text())
+
+	resp, err = s.Get(ts.URL, nil, &RequestParams{Auth: &Auth{Username: "bob", Password: "pw"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "bob:pw", resp.Text(), "RequestParams.Auth should win over .netrc")
+
+	s.DisableNetrc = true
+	resp, err = s.Get(ts.URL, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, ":", resp.Text())
+}
+
+func TestNetrcCrossHostRedirect(t *testing.T) {
+	var other = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer other.Close()
+	var origin = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); !ok {
+			w.WriteHeader(401)
+			return
+		}
+		http.Redirect(w, r, other.URL, http.StatusFound)
+	}))
+	defer origin.Close()
+
+	writeNetrc(t, "default login alice password s3cret\n")
+
+	resp, err := NewSession().Get(origin.URL, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode())
+	assert.Empty(t, resp.Text(), "credentials should not be sent to another host")
+}
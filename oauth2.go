@@ -0,0 +1,244 @@
+package requests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuth2 grant types
+const (
+	GrantClientCredentials = "client_credentials"
+	GrantRefreshToken      = "refresh_token"
+	GrantPassword          = "password"
+)
+
+const defaultExpiryMargin = 30 * time.Second
+
+// OAuth2Error is an error response of the token endpoint, RFC 6749 5.2
+type OAuth2Error struct {
+	StatusCode  int
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *OAuth2Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("go-requests: oauth2: %s: %s", e.Code, e.Description)
+	}
+	return fmt.Sprintf("go-requests: oauth2: %s (status %d)", e.Code, e.StatusCode)
+}
+
+// OAuth2 is an Authenticator which sends a Bearer token fetched from TokenURL.
+// The token is cached until ExpiryMargin (30 seconds by default, at most half
+// of the lifetime of the token) before it expires, and concurrent requests share a single token request. When a
+// server answers 401, the token is dropped and the request is sent once more
+// with a new one.
+type OAuth2 struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// Grant is GrantClientCredentials by default.
+	Grant string
+	// RefreshToken is used by GrantRefreshToken, and by any grant once the
+	// token endpoint has returned one. It is updated when it is rotated.
+	RefreshToken string
+	// Username and Password are used by GrantPassword.
+	Username string
+	Password string
+	// ClientAuthInBody sends ClientID and ClientSecret as form parameters
+	// instead of HTTP Basic authentication.
+	ClientAuthInBody bool
+	ExpiryMargin     time.Duration
+	// Session sends the token requests, without its Auth. nil means the
+	// default Session.
+	Session *Session
+
+	mu      sync.Mutex
+	token   *oauth2Token
+	pending *tokenCall
+}
+
+type oauth2Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	expiry       time.Time
+}
+
+type tokenCall struct {
+	done  chan struct{}
+	token *oauth2Token
+	err   error
+}
+
+// Authenticate implements Authenticator
+func (o *OAuth2) Authenticate(req *http.Request) error {
+	tok, err := o.TokenContext(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+tok)
+	return nil
+}
+
+// Unauthorized expires the token which was rejected, so that the retry fetches a new one
+func (o *OAuth2) Unauthorized(req *http.Request, resp *http.Response) bool {
+	rejected := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.token != nil && o.token.AccessToken == rejected {
+		o.token.expiry = time.Time{}
+	}
+	return true
+}
+
+// Token returns a valid access token, fetching a new one if needed
+func (o *OAuth2) Token() (string, error) {
+	return o.TokenContext(context.Background())
+}
+
+// TokenContext is Token, which stops waiting for a new token when ctx is done.
+// The token request goes on for the next callers.
+func (o *OAuth2) TokenContext(ctx context.Context) (string, error) {
+	o.mu.Lock()
+	if o.token != nil && time.Now().Before(o.token.expiry) {
+		tok := o.token.AccessToken
+		o.mu.Unlock()
+		return tok, nil
+	}
+	call := o.pending
+	if call == nil {
+		call = &tokenCall{done: make(chan struct{})}
+		o.pending = call
+		go o.fetch(call)
+	}
+	o.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	if call.err != nil {
+		return "", call.err
+	}
+	return call.token.AccessToken, nil
+}
+
+func (o *OAuth2) fetch(call *tokenCall) {
+	o.mu.Lock()
+	form := o.tokenForm(o.token != nil)
+	o.mu.Unlock()
+
+	call.token, call.err = o.requestToken(form)
+	var oerr *OAuth2Error
+	if form.Get("grant_type") != defaultString(o.Grant, GrantClientCredentials) && errors.As(call.err, &oerr) {
+		// the refresh token was refused: start again with the original grant
+		o.mu.Lock()
+		form = o.tokenForm(false)
+		o.mu.Unlock()
+		call.token, call.err = o.requestToken(form)
+	}
+
+	o.mu.Lock()
+	if call.err == nil {
+		o.token = call.token
+		if call.token.RefreshToken != "" {
+			o.RefreshToken = call.token.RefreshToken
+		}
+	}
+	o.pending = nil
+	o.mu.Unlock()
+	close(call.done)
+}
+
+func (o *OAuth2) tokenForm(refresh bool) url.Values {
+	form := url.Values{}
+	grant := defaultString(o.Grant, GrantClientCredentials)
+	if refresh && o.RefreshToken != "" {
+		// an expired token is refreshed without the original grant
+		grant = GrantRefreshToken
+	}
+	form.Set("grant_type", grant)
+	switch grant {
+	case GrantRefreshToken:
+		form.Set("refresh_token", o.RefreshToken)
+	case GrantPassword:
+		form.Set("username", o.Username)
+		form.Set("password", o.Password)
+	}
+	if len(o.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.Scopes, " "))
+	}
+	if o.ClientAuthInBody {
+		form.Set("client_id", o.ClientID)
+		if o.ClientSecret != "" {
+			form.Set("client_secret", o.ClientSecret)
+		}
+	}
+	return form
+}
+
+func (o *OAuth2) requestToken(form url.Values) (*oauth2Token, error) {
+	h := make(http.Header)
+	h.Set("Content-Type", "application/x-www-form-urlencoded")
+	h.Set("Accept", "application/json")
+	params := &RequestParams{
+		Data:    bytes.NewBufferString(form.Encode()),
+		Headers: h,
+		// the Session may authenticate with this OAuth2, which would wait for itself
+		noSessionAuth: true,
+	}
+	if !o.ClientAuthInBody && o.ClientID != "" {
+		params.Auth = &Auth{Username: url.QueryEscape(o.ClientID), Password: url.QueryEscape(o.ClientSecret)}
+	}
+	s := o.Session
+	if s == nil {
+		s = defaultSession
+	}
+	resp, err := s.Post(o.TokenURL, nil, params)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() != http.StatusOK {
+		oerr := &OAuth2Error{StatusCode: resp.StatusCode()}
+		if json.Unmarshal(resp.Content(), oerr) != nil || oerr.Code == "" {
+			oerr.Code = resp.Status()
+		}
+		return nil, oerr
+	}
+	tok := &oauth2Token{}
+	if err := resp.Json(tok); err != nil {
+		return nil, err
+	}
+	if tok.AccessToken == "" {
+		return nil, &OAuth2Error{StatusCode: resp.StatusCode(), Code: "invalid_response", Description: "no access_token"}
+	}
+	if tok.TokenType != "" && !strings.EqualFold(tok.TokenType, "bearer") {
+		return nil, &OAuth2Error{StatusCode: resp.StatusCode(), Code: "unsupported_token_type", Description: tok.TokenType}
+	}
+	margin := o.ExpiryMargin
+	if margin == 0 {
+		margin = defaultExpiryMargin
+	}
+	if tok.ExpiresIn > 0 {
+		lifetime := time.Duration(tok.ExpiresIn) * time.Second
+		// a short-lived token is still used for half of its lifetime
+		margin = min(margin, lifetime/2)
+		tok.expiry = time.Now().Add(lifetime - margin)
+	} else {
+		// no expiry given: keep it until the server rejects it
+		tok.expiry = time.Now().Add(100 * 365 * 24 * time.Hour)
+	}
+	return tok, nil
+}
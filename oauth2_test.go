@@ -0,0 +1,198 @@
+package requests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tokenServer struct {
+	*httptest.Server
+	requests atomic.Int32
+	grants   chan string
+}
+
+func newTokenServer(expiresIn int, refresh bool) *tokenServer {
+	ts := &tokenServer{grants: make(chan string, 100)}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if id != "client" || secret != "secret" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(401)
+			w.Write([]byte(`{"error":"invalid_client","error_description":"bad credentials"}`))
+			return
+		}
+		n := ts.requests.Add(1)
+		ts.grants <- r.PostFormValue("grant_type")
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		body := fmt.Sprintf(`{"access_token":"token%d","token_type":"Bearer","expires_in":%d`, n, expiresIn)
+		if refresh {
+			body += fmt.Sprintf(`,"refresh_token":"refresh%d"`, n)
+		}
+		w.Write([]byte(body + "}"))
+	}))
+	return ts
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	tokens := newTokenServer(3600, false)
+	defer tokens.Close()
+
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	s := NewSession()
+	s.Auth = &OAuth2{TokenURL: tokens.URL, ClientID: "client", ClientSecret: "secret", Scopes: []string{"read", "write"}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := s.Get(ts.URL, nil, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, "Bearer token1", resp.Text())
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), tokens.requests.Load(), "concurrent requests should share a single token request")
+	assert.Equal(t, GrantClientCredentials, <-tokens.grants)
+}
+
+func TestOAuth2Expiry(t *testing.T) {
+	tokens := newTokenServer(1, true)
+	defer tokens.Close()
+
+	o := &OAuth2{TokenURL: tokens.URL, ClientID: "client", ClientSecret: "secret", ExpiryMargin: 800 * time.Millisecond}
+
+	tok, err := o.Token()
+	assert.Nil(t, err)
+	assert.Equal(t, "token1", tok)
+	tok, err = o.Token()
+	assert.Nil(t, err)
+	assert.Equal(t, "token1", tok, "token should be cached until it expires")
+
+	time.Sleep(600 * time.Millisecond)
+	tok, err = o.Token()
+	assert.Nil(t, err)
+	assert.Equal(t, "token2", tok)
+	assert.Equal(t, "refresh2", o.RefreshToken, "rotated refresh token should be kept")
+	assert.Equal(t, GrantClientCredentials, <-tokens.grants)
+	assert.Equal(t, GrantRefreshToken, <-tokens.grants)
+}
+
+func TestOAuth2ShortLivedToken(t *testing.T) {
+	tokens := newTokenServer(20, false)
+	defer tokens.Close()
+
+	// the default margin of 30 seconds exceeds expires_in
+	o := &OAuth2{TokenURL: tokens.URL, ClientID: "client", ClientSecret: "secret"}
+	for i := 0; i < 3; i++ {
+		tok, err := o.Token()
+		assert.Nil(t, err)
+		assert.Equal(t, "token1", tok)
+	}
+	assert.Equal(t, int32(1), tokens.requests.Load())
+}
+
+func TestOAuth2RetryOn401(t *testing.T) {
+	tokens := newTokenServer(3600, false)
+	defer tokens.Close()
+
+	var bodies []string
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := new(bytes.Buffer)
+		b.ReadFrom(r.Body)
+		bodies = append(bodies, b.String())
+		if r.Header.Get("Authorization") != "Bearer token2" {
+			w.WriteHeader(401)
+			return
+		}
+		w.WriteHeader(200)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	s := NewSession()
+	s.Auth = &OAuth2{TokenURL: tokens.URL, ClientID: "client", ClientSecret: "secret"}
+
+	resp, err := s.Post(ts.URL, nil, &RequestParams{Data: bytes.NewBufferString("payload")})
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode())
+	assert.Equal(t, []string{"payload", "payload"}, bodies, "body should be sent again")
+
+	bodies = nil
+	s.Auth = &OAuth2{TokenURL: tokens.URL, ClientID: "client", ClientSecret: "secret"}
+	resp, err = s.Get(ts.URL, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 401, resp.StatusCode(), "request should be retried only once")
+	assert.Len(t, bodies, 2)
+}
+
+func TestOAuth2Error(t *testing.T) {
+	tokens := newTokenServer(3600, false)
+	defer tokens.Close()
+
+	o := &OAuth2{TokenURL: tokens.URL, ClientID: "client", ClientSecret: "wrong"}
+	_, err := o.Token()
+	oerr, ok := err.(*OAuth2Error)
+	assert.True(t, ok)
+	assert.Equal(t, "invalid_client", oerr.Code)
+	assert.Equal(t, 401, oerr.StatusCode)
+
+	s := NewSession()
+	s.Auth = o
+	_, err = s.Get(tokens.URL, nil, nil)
+	assert.Equal(t, oerr, err)
+}
+
+func TestOAuth2OwnSession(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			assert.Empty(t, r.Header.Get("Authorization"))
+			assert.Equal(t, "client", r.PostFormValue("client_id"))
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"token1","token_type":"Bearer"}`))
+			return
+		}
+		w.Write([]byte(r.Header.Get("Authorization")))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	s := NewSession()
+	s.Auth = &OAuth2{TokenURL: ts.URL + "/token", ClientID: "client", ClientAuthInBody: true, Session: s}
+	resp, err := s.Get(ts.URL, nil, &RequestParams{Timeout: &Timeout{Connect: time.Second}})
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer token1", resp.Text())
+}
+
+func TestOAuth2Context(t *testing.T) {
+	release := make(chan struct{})
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer tokens.Close()
+	defer close(release)
+
+	s := NewSession()
+	s.Auth = &OAuth2{TokenURL: tokens.URL, ClientID: "client", ClientSecret: "secret"}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := s.Get(tokens.URL, nil, &RequestParams{Context: ctx})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
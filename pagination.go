@@ -0,0 +1,383 @@
+//go:build go1.23
+
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Link is a web link of a Link header, RFC 8288. A link with several
+// relation types is returned once per type.
+type Link struct {
+	URL    string
+	Rel    string
+	Params map[string]string
+}
+
+// Links parses the Link headers of the response. Relative URLs are resolved
+// against the requested URL.
+func (resp Response) Links() []Link {
+	var links []Link
+	for _, v := range resp.headers.Values("Link") {
+		for _, l := range parseLinks(v) {
+			if resp._url != nil {
+				if u, err := resp._url.Parse(l.URL); err == nil {
+					l.URL = u.String()
+				}
+			}
+			links = append(links, l)
+		}
+	}
+	return links
+}
+
+func parseLinks(s string) []Link {
+	var links []Link
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if !strings.HasPrefix(s, "<") {
+			return links
+		}
+		end := strings.IndexByte(s, '>')
+		if end < 0 {
+			return links
+		}
+		target := s[1:end]
+		s = s[end+1:]
+
+		params := map[string]string{}
+		for {
+			s = strings.TrimLeft(s, " \t")
+			if !strings.HasPrefix(s, ";") {
+				break
+			}
+			s = strings.TrimLeft(s[1:], " \t")
+			i := strings.IndexAny(s, "=;,")
+			if i < 0 {
+				i = len(s)
+			}
+			name := strings.ToLower(strings.TrimSpace(s[:i]))
+			s = s[i:]
+			value := ""
+			if strings.HasPrefix(s, "=") {
+				value, s = parseLinkParamValue(strings.TrimLeft(s[1:], " \t"))
+			}
+			if _, ok := params[name]; !ok && name != "" {
+				// only the first occurrence of a parameter counts
+				params[name] = value
+			}
+		}
+		rels := strings.Fields(params["rel"])
+		if len(rels) == 0 {
+			rels = []string{""}
+		}
+		for _, rel := range rels {
+			links = append(links, Link{URL: target, Rel: strings.ToLower(rel), Params: params})
+		}
+	}
+}
+
+func parseLinkParamValue(s string) (string, string) {
+	if !strings.HasPrefix(s, `"`) {
+		i := strings.IndexAny(s, ";,")
+		if i < 0 {
+			i = len(s)
+		}
+		return strings.TrimSpace(s[:i]), s[i:]
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), ""
+}
+
+// Pagination finds the page after resp, which was fetched from current.
+// It returns nil when resp is the last page.
+type Pagination interface {
+	NextPage(resp Response, current *url.URL) (*url.URL, error)
+}
+
+// LinkPagination follows the rel="next" Link header, like the GitHub API
+type LinkPagination struct{}
+
+// NextPage implements Pagination
+func (LinkPagination) NextPage(resp Response, current *url.URL) (*url.URL, error) {
+	for _, l := range resp.Links() {
+		if l.Rel == "next" {
+			return current.Parse(l.URL)
+		}
+	}
+	return nil, nil
+}
+
+// CursorPagination reads the next cursor from the JSON body at Path, like
+// "meta.next_cursor", and sends it as the query parameter Param ("cursor" by
+// default). A cursor which is an absolute URL is followed as is.
+type CursorPagination struct {
+	Path  string
+	Param string
+}
+
+// NextPage implements Pagination
+func (p CursorPagination) NextPage(resp Response, current *url.URL) (*url.URL, error) {
+	v, err := jsonPath(resp.Content(), p.Path)
+	if err != nil {
+		return nil, err
+	}
+	var cursor string
+	switch c := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		cursor = c
+	case json.Number:
+		cursor = c.String()
+	default:
+		return nil, fmt.Errorf("go-requests: cursor at %q is not a string", p.Path)
+	}
+	if cursor == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(cursor, "http://") || strings.HasPrefix(cursor, "https://") {
+		return url.Parse(cursor)
+	}
+	return withQuery(current, defaultString(p.Param, "cursor"), cursor), nil
+}
+
+// PagePagination increments the page number in the query parameter Param,
+// "page" by default. A request without it is page Start, 1 by default.
+type PagePagination struct {
+	Param string
+	Start int
+}
+
+// NextPage implements Pagination
+func (p PagePagination) NextPage(resp Response, current *url.URL) (*url.URL, error) {
+	param := defaultString(p.Param, "page")
+	page := p.Start
+	if page == 0 {
+		page = 1
+	}
+	if v := current.Query().Get(param); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("go-requests: invalid page number %q", v)
+		}
+		page = n
+	}
+	return withQuery(current, param, strconv.Itoa(page+1)), nil
+}
+
+// OffsetPagination advances the query parameter OffsetParam ("offset" by
+// default) by Limit, and sends Limit as LimitParam ("limit" by default).
+type OffsetPagination struct {
+	OffsetParam string
+	LimitParam  string
+	Limit       int
+}
+
+// NextPage implements Pagination
+func (p OffsetPagination) NextPage(resp Response, current *url.URL) (*url.URL, error) {
+	if p.Limit <= 0 {
+		return nil, errors.New("go-requests: OffsetPagination needs a Limit")
+	}
+	param := defaultString(p.OffsetParam, "offset")
+	offset := 0
+	if v := current.Query().Get(param); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("go-requests: invalid offset %q", v)
+		}
+		offset = n
+	}
+	next := withQuery(current, param, strconv.Itoa(offset+p.Limit))
+	return withQuery(next, defaultString(p.LimitParam, "limit"), strconv.Itoa(p.Limit)), nil
+}
+
+func withQuery(u *url.URL, key, value string) *url.URL {
+	next := *u
+	q := next.Query()
+	q.Set(key, value)
+	next.RawQuery = q.Encode()
+	return &next
+}
+
+// Paginator describes how to walk through a paginated API
+type Paginator struct {
+	// Pagination finds the next page.
+	Pagination Pagination
+	// ItemsPath is the dotted path to the array of items in a JSON page, like
+	// "data.items". Empty means the page itself. Pagination stops at an empty
+	// array. A page without the array is an error.
+	ItemsPath string
+	// MaxPages stops after that many pages. 0 means no limit.
+	MaxPages int
+	// Method is the HTTP method, GET by default.
+	Method string
+}
+
+// Paginate fetches the pages starting at urlStr with the default Session
+func Paginate(p Paginator, urlStr string, queryString *url.Values, r *RequestParams) iter.Seq2[Response, error] {
+	return defaultSession.Paginate(p, urlStr, queryString, r)
+}
+
+// Paginate fetches the pages starting at urlStr with given queryString and
+// RequestParams, lazily, one page per iteration. It stops at the last or an
+// empty page, after MaxPages, when RequestParams.Context is done, or after an
+// error. A page with a status other than 2xx is yielded with an error, and so
+// is a page which is not JSON for the cursor, page number and offset paginations.
+func (s *Session) Paginate(p Paginator, urlStr string, queryString *url.Values, r *RequestParams) iter.Seq2[Response, error] {
+	return func(yield func(Response, error) bool) {
+		if p.Pagination == nil {
+			yield(Response{}, errors.New("go-requests: Paginator needs a Pagination"))
+			return
+		}
+		current, err := parseURL(urlStr)
+		if err != nil {
+			yield(Response{}, err)
+			return
+		}
+		if queryString != nil {
+			current.RawQuery = queryString.Encode()
+		}
+		method := defaultString(p.Method, http.MethodGet)
+		ctx := requestContext(r)
+		var params RequestParams
+		if r != nil {
+			params = *r
+		}
+		// pages are inspected, so they are always buffered
+		params.Stream = false
+		// the body is sent again for every page
+		var data []byte
+		if params.Data != nil {
+			data = params.Data.Bytes()
+		}
+
+		for page := 0; p.MaxPages == 0 || page < p.MaxPages; page++ {
+			if err := ctx.Err(); err != nil {
+				yield(Response{}, err)
+				return
+			}
+			if data != nil {
+				params.Data = bytes.NewBuffer(data)
+			}
+			resp, err := s.send(method, current.String(), nil, &params)
+			if err != nil {
+				yield(Response{}, err)
+				return
+			}
+			if resp.StatusCode() < 200 || resp.StatusCode() > 299 {
+				yield(resp, fmt.Errorf("go-requests: page %s returned %s", current, resp.Status()))
+				return
+			}
+			empty, err := emptyPage(resp, p.ItemsPath, p.jsonPages())
+			if err != nil {
+				yield(resp, err)
+				return
+			}
+			if empty {
+				return
+			}
+			if !yield(resp, nil) {
+				return
+			}
+			next, err := p.Pagination.NextPage(resp, current)
+			if err != nil {
+				yield(Response{}, err)
+				return
+			}
+			if next == nil {
+				return
+			}
+			current = next
+		}
+	}
+}
+
+// jsonPages reports whether the pages must be JSON, to find the items or
+// the next page in them
+func (p Paginator) jsonPages() bool {
+	if p.ItemsPath != "" {
+		return true
+	}
+	switch p.Pagination.(type) {
+	case CursorPagination, *CursorPagination, PagePagination, *PagePagination, OffsetPagination, *OffsetPagination:
+		return true
+	}
+	return false
+}
+
+// emptyPage reports whether resp has no items
+func emptyPage(resp Response, itemsPath string, isJSON bool) (bool, error) {
+	if len(strings.TrimSpace(resp.Text())) == 0 {
+		return true, nil
+	}
+	v, err := jsonPath(resp.Content(), itemsPath)
+	if err != nil {
+		if !isJSON {
+			return false, nil
+		}
+		return false, fmt.Errorf("go-requests: page is not JSON: %w", err)
+	}
+	items, ok := v.([]interface{})
+	if !ok && itemsPath != "" {
+		return false, fmt.Errorf("go-requests: no array of items at %q", itemsPath)
+	}
+	return ok && len(items) == 0, nil
+}
+
+// PaginateItems decodes the items at itemsPath of each page as T
+func PaginateItems[T any](pages iter.Seq2[Response, error], itemsPath string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for resp, err := range pages {
+			var items []T
+			if err == nil {
+				items, err = decodeItems[T](resp, itemsPath)
+			}
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func decodeItems[T any](resp Response, itemsPath string) ([]T, error) {
+	v, err := jsonPath(resp.Content(), itemsPath)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var items []T
+	err = json.Unmarshal(b, &items)
+	return items, err
+}
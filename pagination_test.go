@@ -0,0 +1,234 @@
+//go:build go1.23
+
+package requests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinks(t *testing.T) {
+	resp := Response{
+		_url: &url.URL{Scheme: "https", Host: "api.github.com", Path: "/repos"},
+		headers: http.Header{"Link": {
+			`<https://api.github.com/repos?page=2>; rel="next", </repos?page=5>; rel="last"`,
+			`<https://example.com/a;b>; rel="prev start"; title="a, \"quoted\"; title"; type=text/html`,
+		}},
+	}
+	links := resp.Links()
+	assert.Equal(t, 4, len(links))
+	assert.Equal(t, Link{URL: "https://api.github.com/repos?page=2", Rel: "next", Params: map[string]string{"rel": "next"}}, links[0])
+	assert.Equal(t, "https://api.github.com/repos?page=5", links[1].URL)
+	assert.Equal(t, "last", links[1].Rel)
+	assert.Equal(t, "https://example.com/a;b", links[2].URL)
+	assert.Equal(t, "prev", links[2].Rel)
+	assert.Equal(t, "start", links[3].Rel)
+	assert.Equal(t, `a, "quoted"; title`, links[3].Params["title"])
+	assert.Equal(t, "text/html", links[3].Params["type"])
+}
+
+func TestPaginateLinkHeader(t *testing.T) {
+	var ts *httptest.Server
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 3 {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/items?page=%d>; rel="next"`, ts.URL, page+1))
+		}
+		fmt.Fprintf(w, `[{"id":%d}]`, page)
+	})
+	ts = httptest.NewServer(handler)
+	defer ts.Close()
+
+	type item struct {
+		ID int `json:"id"`
+	}
+	var ids []int
+	pages := Paginate(Paginator{Pagination: LinkPagination{}}, ts.URL+"/items", &url.Values{"page": {"1"}}, nil)
+	for it, err := range PaginateItems[item](pages, "") {
+		assert.Nil(t, err)
+		ids = append(ids, it.ID)
+	}
+	assert.Equal(t, []int{1, 2, 3}, ids)
+}
+
+func TestPaginateCursor(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			w.Write([]byte(`{"data":[1,2],"meta":{"next_cursor":"abc"}}`))
+		case "abc":
+			w.Write([]byte(`{"data":[3],"meta":{"next_cursor":null}}`))
+		}
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	var got []int
+	p := Paginator{Pagination: CursorPagination{Path: "meta.next_cursor"}, ItemsPath: "data"}
+	for n, err := range PaginateItems[int](Paginate(p, ts.URL, nil, nil), p.ItemsPath) {
+		assert.Nil(t, err)
+		got = append(got, n)
+	}
+	assert.Equal(t, []int{1, 2, 3}, got)
+}
+
+func TestPaginatePageNumberStopsOnEmptyPage(t *testing.T) {
+	var requested []string
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.RawQuery)
+		if r.URL.Query().Get("page") == "3" {
+			w.Write([]byte(`{"items":[]}`))
+			return
+		}
+		w.Write([]byte(`{"items":["x"]}`))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	count := 0
+	p := Paginator{Pagination: PagePagination{}, ItemsPath: "items"}
+	for _, err := range Paginate(p, ts.URL, nil, nil) {
+		assert.Nil(t, err)
+		count++
+	}
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []string{"", "page=2", "page=3"}, requested)
+}
+
+func TestPaginateOffsetMaxPages(t *testing.T) {
+	var requested []string
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.RawQuery)
+		w.Write([]byte(`[1]`))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	p := Paginator{Pagination: OffsetPagination{Limit: 10}, MaxPages: 3}
+	for _, err := range Paginate(p, ts.URL, &url.Values{"limit": {"10"}}, nil) {
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []string{"limit=10", "limit=10&offset=10", "limit=10&offset=20"}, requested)
+}
+
+func TestPaginateContext(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[1]`))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var errs []error
+	for _, err := range Paginate(Paginator{Pagination: PagePagination{}}, ts.URL, nil, &RequestParams{Context: ctx}) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		cancel()
+	}
+	assert.Equal(t, []error{context.Canceled}, errs)
+}
+
+func TestPaginateErrorStatus(t *testing.T) {
+	sent := 0
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("<html>not found</html>"))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	var errs []error
+	for resp, err := range Paginate(Paginator{Pagination: PagePagination{}}, ts.URL, nil, nil) {
+		assert.Equal(t, 404, resp.StatusCode())
+		errs = append(errs, err)
+	}
+	assert.Equal(t, 1, sent)
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "go-requests: page "+ts.URL+" returned 404 Not Found")
+}
+
+func TestPaginateNotJSON(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>items</html>"))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	var errs []error
+	for _, err := range Paginate(Paginator{Pagination: PagePagination{}}, ts.URL, nil, nil) {
+		errs = append(errs, err)
+	}
+	assert.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "go-requests: page is not JSON")
+
+	// the Link header needs no JSON
+	count := 0
+	for _, err := range Paginate(Paginator{Pagination: LinkPagination{}}, ts.URL, nil, nil) {
+		assert.Nil(t, err)
+		count++
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestPaginateMissingItems(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{}}`))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	var errs []error
+	for _, err := range Paginate(Paginator{Pagination: OffsetPagination{Limit: 10}, ItemsPath: "data.items"}, ts.URL, nil, nil) {
+		errs = append(errs, err)
+	}
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], `go-requests: no array of items at "data.items"`)
+}
+
+func TestPaginatePostBody(t *testing.T) {
+	var bodies []string
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		w.Write([]byte(`{"items":["x"]}`))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	p := Paginator{Pagination: PagePagination{}, ItemsPath: "items", MaxPages: 3, Method: http.MethodPost}
+	for _, err := range Paginate(p, ts.URL, nil, &RequestParams{Data: bytes.NewBufferString(`{"q":"go"}`)}) {
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []string{`{"q":"go"}`, `{"q":"go"}`, `{"q":"go"}`}, bodies)
+}
+
+func TestPaginateNoPagination(t *testing.T) {
+	sent := 0
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		w.Write([]byte(`["x"]`))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	var errs []error
+	for _, err := range Paginate(Paginator{}, ts.URL, nil, nil) {
+		errs = append(errs, err)
+	}
+	assert.Equal(t, 0, sent)
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "go-requests: Paginator needs a Pagination")
+}
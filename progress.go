@@ -0,0 +1,112 @@
+package requests
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+const defaultProgressInterval = 200 * time.Millisecond
+
+// Progress reports how far an upload or a download has got
+type Progress struct {
+	// Transferred is the number of bytes sent or received so far.
+	Transferred int64
+	// Total is the size of the body, or -1 if it is not known.
+	Total int64
+	// Rate is the average transfer rate in bytes per second.
+	Rate float64
+	// ETA is the estimated time left, or 0 if it is not known.
+	ETA time.Duration
+	// Done is set on the last report, when the whole body has been transferred.
+	Done bool
+}
+
+// progress calls fn with the progress of a transfer, at most once per interval
+type progress struct {
+	mu       sync.Mutex
+	fn       func(Progress)
+	interval time.Duration
+	total    int64
+	n        int64
+	start    time.Time
+	last     time.Time
+	done     bool
+}
+
+func newProgress(fn func(Progress), interval time.Duration, total int64) *progress {
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+	now := time.Now()
+	return &progress{fn: fn, interval: interval, total: total, start: now, last: now}
+}
+
+func (p *progress) add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.n += n
+	if p.total > 0 && p.n >= p.total {
+		p.finishLocked()
+	} else if now := time.Now(); now.Sub(p.last) >= p.interval {
+		p.last = now
+		p.report(now)
+	}
+}
+
+func (p *progress) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.finishLocked()
+}
+
+func (p *progress) finishLocked() {
+	if p.done {
+		return
+	}
+	p.done = true
+	if p.total < 0 {
+		p.total = p.n
+	}
+	p.report(time.Now())
+}
+
+func (p *progress) report(now time.Time) {
+	pr := Progress{Transferred: p.n, Total: p.total, Done: p.done}
+	if elapsed := now.Sub(p.start).Seconds(); elapsed > 0 {
+		pr.Rate = float64(p.n) / elapsed
+	}
+	if p.total > 0 && pr.Rate > 0 && !p.done {
+		pr.ETA = time.Duration(float64(p.total-p.n) / pr.Rate * float64(time.Second))
+	}
+	p.fn(pr)
+}
+
+// progressReader reports what is read through it. The transfer is finished at EOF,
+// unless the reader is only a part of it.
+type progressReader struct {
+	io.ReadCloser
+	p    *progress
+	part bool
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	r.p.add(int64(n))
+	if err == io.EOF && !r.part {
+		r.p.finish()
+	}
+	return n, err
+}
+
+// keepWriter returns wrapped with the Write method of body, a response body
+// wrapped by it. The body of 101 Switching Protocols is the writable connection.
+func keepWriter(wrapped, body io.ReadCloser) io.ReadCloser {
+	if w, ok := body.(io.Writer); ok {
+		return struct {
+			io.ReadCloser
+			io.Writer
+		}{wrapped, w}
+	}
+	return wrapped
+}
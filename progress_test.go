@@ -0,0 +1,107 @@
+package requests
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadProgress(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		assert.Equal(t, int64(100000), r.ContentLength)
+		assert.Equal(t, 100000, len(b))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	var reports []Progress
+	_, err := Post(ts.URL, nil, &RequestParams{
+		Data:             bytes.NewBuffer(make([]byte, 100000)),
+		UploadProgress:   func(p Progress) { reports = append(reports, p) },
+		ProgressInterval: time.Nanosecond,
+	})
+	assert.Nil(t, err)
+	assert.True(t, len(reports) > 0)
+	last := reports[len(reports)-1]
+	assert.True(t, last.Done)
+	assert.Equal(t, int64(100000), last.Transferred)
+	assert.Equal(t, int64(100000), last.Total)
+	assert.Equal(t, 1, countDone(reports))
+}
+
+func TestDownloadProgress(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 50000)))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	for _, stream := range []bool{false, true} {
+		var reports []Progress
+		resp, err := Get(ts.URL, nil, &RequestParams{
+			Stream:           stream,
+			DownloadProgress: func(p Progress) { reports = append(reports, p) },
+			ProgressInterval: time.Nanosecond,
+		})
+		assert.Nil(t, err)
+		body := resp.Body()
+		b, _ := io.ReadAll(body)
+		body.Close()
+		assert.Equal(t, 50000, len(b))
+
+		last := reports[len(reports)-1]
+		assert.True(t, last.Done)
+		assert.Equal(t, int64(50000), last.Transferred)
+		assert.Equal(t, int64(50000), last.Total)
+		assert.True(t, last.Rate > 0)
+		assert.Equal(t, 1, countDone(reports))
+	}
+}
+
+func TestDownloadProgressSegments(t *testing.T) {
+	content := strings.Repeat("0123456789", 1000)
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	var (
+		mu      sync.Mutex
+		last    Progress
+		reports []Progress
+	)
+	d := &Downloader{Segments: 3}
+	_, err := d.Download(ts.URL, filepath.Join(t.TempDir(), "f"), &RequestParams{
+		DownloadProgress: func(p Progress) {
+			mu.Lock()
+			last = p
+			reports = append(reports, p)
+			mu.Unlock()
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, countDone(reports), reports)
+	assert.True(t, last.Done)
+	assert.Equal(t, int64(10000), last.Transferred)
+	assert.Equal(t, int64(10000), last.Total)
+}
+
+func countDone(reports []Progress) int {
+	n := 0
+	for _, p := range reports {
+		if p.Done {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,189 @@
+package requests
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned when RateLimiter.FailFast is set and no token is available
+var ErrRateLimited = errors.New("go-requests: rate limit exceeded")
+
+// RateLimit is a token bucket: Rate requests per second with bursts up to Burst
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimiter throttles requests per host. Hosts maps host patterns such as
+// "api.example.com" or "*.example.com" to a limit shared by every matching host.
+// Default, if set, is applied to each other host separately. When several
+// patterns match, an exact host wins over wildcards, then the longest pattern.
+//
+// The limiter also backs off when a response carries Retry-After, or
+// RateLimit-Remaining/X-RateLimit-Remaining of 0 with a matching *-Reset header.
+type RateLimiter struct {
+	Default  *RateLimit
+	Hosts    map[string]RateLimit
+	FailFast bool
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	limit  *RateLimit
+	tokens float64
+	last   time.Time
+	until  time.Time // blocked until, set from response headers
+}
+
+func (l *RateLimiter) bucket(host string) *bucket {
+	key, limit := host, l.Default
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	matched := false
+	for pattern, rl := range l.Hosts {
+		if !matchHost(pattern, host) && !matchHost(pattern, hostname) {
+			continue
+		}
+		if !matched || moreSpecific(pattern, key) {
+			key, limit = pattern, &RateLimit{Rate: rl.Rate, Burst: rl.Burst}
+			matched = true
+		}
+	}
+	if l.buckets == nil {
+		l.buckets = make(map[string]*bucket)
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limit: limit, last: time.Now()}
+		if limit != nil {
+			b.tokens = float64(limit.burst())
+		}
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// moreSpecific orders matching patterns, so that the choice does not depend
+// on the order of the map
+func moreSpecific(a, b string) bool {
+	exactA, exactB := !strings.ContainsAny(a, `*?[\`), !strings.ContainsAny(b, `*?[\`)
+	if exactA != exactB {
+		return exactA
+	}
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a < b
+}
+
+func matchHost(pattern, host string) bool {
+	ok, _ := path.Match(pattern, host)
+	return ok
+}
+
+func (rl *RateLimit) burst() int {
+	if rl.Burst < 1 {
+		return 1
+	}
+	return rl.Burst
+}
+
+// reserve takes a token or returns how long to wait for one
+func (b *bucket) reserve(now time.Time) time.Duration {
+	if now.Before(b.until) {
+		return b.until.Sub(now)
+	}
+	if b.limit == nil || b.limit.Rate <= 0 {
+		return 0
+	}
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+	if max := float64(b.limit.burst()); b.tokens > max {
+		b.tokens = max
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second))
+}
+
+func (l *RateLimiter) wait(ctx context.Context, host string) error {
+	for {
+		l.mu.Lock()
+		d := l.bucket(host).reserve(time.Now())
+		l.mu.Unlock()
+		if d <= 0 {
+			return nil
+		}
+		if l.FailFast {
+			return ErrRateLimited
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (l *RateLimiter) update(host string, resp *http.Response) {
+	until, ok := retryAfter(resp.Header)
+	if !ok {
+		until, ok = rateLimitReset(resp.Header)
+	}
+	if !ok {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b := l.bucket(host); until.After(b.until) {
+		b.until = until
+	}
+}
+
+func retryAfter(h http.Header) (time.Time, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return time.Time{}, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Now().Add(time.Duration(secs) * time.Second), true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+func rateLimitReset(h http.Header) (time.Time, bool) {
+	for _, prefix := range []string{"RateLimit-", "X-RateLimit-"} {
+		remaining := strings.TrimSpace(h.Get(prefix + "Remaining"))
+		if remaining != "0" {
+			continue
+		}
+		reset, err := strconv.ParseInt(strings.TrimSpace(h.Get(prefix+"Reset")), 10, 64)
+		if err != nil {
+			continue
+		}
+		// X-RateLimit-Reset is commonly a unix time, RateLimit-Reset is delta seconds
+		if reset > 1e9 {
+			return time.Unix(reset, 0), true
+		}
+		return time.Now().Add(time.Duration(reset) * time.Second), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,117 @@
+package requests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterBlocks(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	s := NewSession()
+	s.RateLimiter = &RateLimiter{
+		Default: &RateLimit{Rate: 10, Burst: 1},
+	}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := s.Get(ts.URL, nil, nil)
+		assert.Nil(t, err)
+	}
+	assert.True(t, time.Since(start) >= 180*time.Millisecond, "3 requests at 10 req/s should take about 200ms")
+}
+
+func TestRateLimiterFailFast(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	s := NewSession()
+	s.RateLimiter = &RateLimiter{
+		Hosts:    map[string]RateLimit{"127.0.0.1": {Rate: 1, Burst: 1}},
+		FailFast: true,
+	}
+
+	_, err := s.Get(ts.URL, nil, nil)
+	assert.Nil(t, err)
+	_, err = s.Get(ts.URL, nil, nil)
+	assert.Equal(t, ErrRateLimited, err)
+}
+
+func TestRateLimiterContextCancel(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	s := NewSession()
+	s.RateLimiter = &RateLimiter{
+		Default: &RateLimit{Rate: 0.1, Burst: 1},
+	}
+	_, err := s.Get(ts.URL, nil, nil)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = s.Get(ts.URL, nil, &RequestParams{Context: ctx})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestRateLimiterRetryAfter(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	s := NewSession()
+	s.RateLimiter = &RateLimiter{FailFast: true}
+
+	resp, err := s.Get(ts.URL, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 429, resp.StatusCode())
+	_, err = s.Get(ts.URL, nil, nil)
+	assert.Equal(t, ErrRateLimited, err)
+}
+
+func TestRateLimitReset(t *testing.T) {
+	h := http.Header{}
+	h.Set("X-RateLimit-Remaining", "0")
+	h.Set("X-RateLimit-Reset", "30")
+	until, ok := rateLimitReset(h)
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), until, time.Second)
+
+	h.Set("X-RateLimit-Remaining", "10")
+	_, ok = rateLimitReset(h)
+	assert.False(t, ok)
+}
+
+func TestRateLimiterMostSpecificPattern(t *testing.T) {
+	l := &RateLimiter{Hosts: map[string]RateLimit{
+		"*":                 {Rate: 1},
+		"*.com":             {Rate: 2},
+		"*.example.com":     {Rate: 3},
+		"api.example.com":   {Rate: 4},
+		"api.example.com:*": {Rate: 5},
+	}}
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, 4.0, l.bucket("api.example.com:443").limit.Rate)
+		assert.Equal(t, 3.0, l.bucket("www.example.com").limit.Rate)
+		assert.Equal(t, 2.0, l.bucket("golang.com").limit.Rate)
+		assert.Equal(t, 1.0, l.bucket("localhost").limit.Rate)
+	}
+}
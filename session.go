@@ -0,0 +1,122 @@
+package requests
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Session keeps settings shared by every request made through it. The package
+// level functions such as Get and Post use a default Session.
+type Session struct {
+	// Auth adds credentials to every request without RequestParams.Auth.
+	Auth Authenticator
+	// DisableNetrc stops looking up credentials for requests without any in
+	// .netrc, or in the file named by the NETRC environment variable.
+	DisableNetrc bool
+	// CurlLog, when set, receives every request sent, including redirects and
+	// retries, as a curl command rendered with CurlOptions.
+	CurlLog     func(cmd string)
+	CurlOptions *CurlOptions
+	// Tracer receives a span for every request sent. Requests with a
+	// RequestParams.Context of ContextWithTrace continue its trace with
+	// traceparent and tracestate headers, also without a Tracer. With a
+	// Tracer, other requests continue the traceparent header they have, or
+	// start a new trace.
+	Tracer Tracer
+	// Metrics, when set, records every request sent, see Metrics.
+	Metrics *Metrics
+	// RateLimiter throttles requests before they are sent. nil means no limit.
+	RateLimiter *RateLimiter
+	// CircuitBreaker refuses requests to failing hosts. nil disables it.
+	CircuitBreaker *CircuitBreaker
+	// Transport tunes connections. nil uses the defaults of http.DefaultTransport.
+	// It is built on first use, so assign a new *Transport to change it.
+	Transport *Transport
+
+	client *client
+}
+
+var defaultSession = NewSession()
+
+// NewSession returns a new Session
+func NewSession() *Session {
+	s := &Session{}
+	s.client = newClient(s)
+	return s
+}
+
+// Close releases idle connections held by the Session
+func (s *Session) Close() error {
+	s.client.closeIdleConnections()
+	return nil
+}
+
+// Head makes HTTP(s) HEAD request with given urlStr, queryString and RequestParams
+func (s *Session) Head(urlStr string, queryString *url.Values, r *RequestParams) (Response, error) {
+	return s.send(http.MethodHead, urlStr, queryString, r)
+}
+
+// HeadAsync makes asynchronous HTTP(s) HEAD request with given urlStr, queryString and RequestParams
+func (s *Session) HeadAsync(urlStr string, queryString *url.Values, r *RequestParams) (chan Response, chan error) {
+	return s.sendAsync(http.MethodHead, urlStr, queryString, r)
+}
+
+// Get makes HTTP(s) GET request with given urlStr, queryString and RequestParams
+func (s *Session) Get(urlStr string, queryString *url.Values, r *RequestParams) (Response, error) {
+	return s.send(http.MethodGet, urlStr, queryString, r)
+}
+
+// GetAsync makes asynchronous HTTP(s) GET request with given urlStr, queryString and RequestParams
+func (s *Session) GetAsync(urlStr string, queryString *url.Values, r *RequestParams) (chan Response, chan error) {
+	return s.sendAsync(http.MethodGet, urlStr, queryString, r)
+}
+
+// Post makes HTTP(s) POST request with given urlStr, queryString and RequestParams
+func (s *Session) Post(urlStr string, queryString *url.Values, r *RequestParams) (Response, error) {
+	return s.send(http.MethodPost, urlStr, queryString, r)
+}
+
+// PostAsync makes asynchronous HTTP(s) POST request with given urlStr, queryString and RequestParams
+func (s *Session) PostAsync(urlStr string, queryString *url.Values, r *RequestParams) (chan Response, chan error) {
+	return s.sendAsync(http.MethodPost, urlStr, queryString, r)
+}
+
+// Put makes HTTP(s) PUT request with given urlStr, queryString and RequestParams
+func (s *Session) Put(urlStr string, queryString *url.Values, r *RequestParams) (Response, error) {
+	return s.send(http.MethodPut, urlStr, queryString, r)
+}
+
+// PutAsync makes asynchronous HTTP(s) PUT request with given urlStr, queryString and RequestParams
+func (s *Session) PutAsync(urlStr string, queryString *url.Values, r *RequestParams) (chan Response, chan error) {
+	return s.sendAsync(http.MethodPut, urlStr, queryString, r)
+}
+
+// Patch makes HTTP(s) PATCH request with given urlStr, queryString and RequestParams
+func (s *Session) Patch(urlStr string, queryString *url.Values, r *RequestParams) (Response, error) {
+	return s.send(http.MethodPatch, urlStr, queryString, r)
+}
+
+// PatchAsync makes asynchronous HTTP(s) PATCH request with given urlStr, queryString and RequestParams
+func (s *Session) PatchAsync(urlStr string, queryString *url.Values, r *RequestParams) (chan Response, chan error) {
+	return s.sendAsync(http.MethodPatch, urlStr, queryString, r)
+}
+
+// Delete makes HTTP(s) DELETE request with given urlStr, queryString and RequestParams
+func (s *Session) Delete(urlStr string, queryString *url.Values, r *RequestParams) (Response, error) {
+	return s.send(http.MethodDelete, urlStr, queryString, r)
+}
+
+// DeleteAsync makes asynchronous HTTP(s) DELETE request with given urlStr, queryString and RequestParams
+func (s *Session) DeleteAsync(urlStr string, queryString *url.Values, r *RequestParams) (chan Response, chan error) {
+	return s.sendAsync(http.MethodDelete, urlStr, queryString, r)
+}
+
+// Options makes HTTP(s) OPTIONS request with given urlStr, queryString and RequestParams
+func (s *Session) Options(urlStr string, queryString *url.Values, r *RequestParams) (Response, error) {
+	return s.send(http.MethodOptions, urlStr, queryString, r)
+}
+
+// OptionsAsync makes asynchronous HTTP(s) OPTIONS request with given urlStr, queryString and RequestParams
+func (s *Session) OptionsAsync(urlStr string, queryString *url.Values, r *RequestParams) (chan Response, chan error) {
+	return s.sendAsync(http.MethodOptions, urlStr, queryString, r)
+}
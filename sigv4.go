@@ -0,0 +1,371 @@
+package requests
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm    = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat   = "20060102T150405Z"
+	emptyPayloadHash  = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	unsignedPayload   = "UNSIGNED-PAYLOAD"
+	streamingPayload  = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	defaultChunkSize  = 64 * 1024
+	chunkSignatureLen = len(";chunk-signature=") + 64
+)
+
+// SigV4Payload is how the body of a request is covered by a SigV4 signature
+type SigV4Payload int
+
+const (
+	// SignedPayload signs the SHA-256 hash of the body, which is read once in advance.
+	SignedPayload SigV4Payload = iota
+	// UnsignedPayload leaves the body out of the signature.
+	UnsignedPayload
+	// StreamingPayload sends the body in aws-chunked encoding, each chunk
+	// signed as it is sent. The content length must be known.
+	StreamingPayload
+)
+
+// SigV4 is an Authenticator which signs requests with AWS Signature Version 4,
+// for AWS services and S3 compatible storage such as MinIO
+type SigV4 struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is sent as X-Amz-Security-Token for temporary credentials.
+	SessionToken string
+	Region       string
+	// Service is the signing name of the service, like "s3" or "execute-api".
+	Service string
+	Payload SigV4Payload
+	// ChunkSize is the size of the chunks of StreamingPayload, 64KiB by default.
+	ChunkSize int
+	// SignedHeaders are signed besides Host, Content-Type, Content-MD5,
+	// Content-Encoding, Range and the X-Amz-* headers, like "If-Match". Other
+	// headers are not signed.
+	SignedHeaders []string
+
+	now func() time.Time
+}
+
+// SigV4FromEnv returns a SigV4 for service with the credentials and the region
+// in AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN and AWS_REGION
+// (or AWS_DEFAULT_REGION)
+func SigV4FromEnv(service string) (*SigV4, error) {
+	s := &SigV4{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		Region:          defaultString(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION")),
+		Service:         service,
+	}
+	if s.AccessKeyID == "" || s.SecretAccessKey == "" {
+		return nil, errors.New("go-requests: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are not set")
+	}
+	if s.Region == "" {
+		return nil, errors.New("go-requests: AWS_REGION is not set")
+	}
+	return s, nil
+}
+
+// Authenticate implements Authenticator
+func (s *SigV4) Authenticate(req *http.Request) error {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	t := now().UTC()
+	amzDate := t.Format(sigV4TimeFormat)
+	scope := strings.Join([]string{t.Format("20060102"), s.Region, s.Service, "aws4_request"}, "/")
+
+	req.Header.Del("Authorization")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	var payloadHash string
+	switch s.Payload {
+	case UnsignedPayload:
+		payloadHash = unsignedPayload
+	case StreamingPayload:
+		payloadHash = streamingPayload
+		if err := s.prepareStreaming(req); err != nil {
+			return err
+		}
+	default:
+		var err error
+		if payloadHash, err = hashBody(req); err != nil {
+			return err
+		}
+	}
+	if s.Service == "s3" || s.Payload != SignedPayload {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	headers, signedHeaders := s.canonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s.canonicalURI(req.URL),
+		canonicalQuery(req.URL),
+		headers,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		amzDate,
+		scope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+	key := s.signingKey(t)
+	signature := hex.EncodeToString(hmacSHA256(key, []byte(stringToSign)))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, s.AccessKeyID, scope, signedHeaders, signature))
+
+	if cr, ok := req.Body.(*awsChunkedReader); ok {
+		cr.key, cr.date, cr.scope, cr.prev = key, amzDate, scope, signature
+	}
+	return nil
+}
+
+func (s *SigV4) signingKey(t time.Time) []byte {
+	k := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), []byte(t.Format("20060102")))
+	k = hmacSHA256(k, []byte(s.Region))
+	k = hmacSHA256(k, []byte(s.Service))
+	return hmacSHA256(k, []byte("aws4_request"))
+}
+
+// hashBody returns the SHA-256 of the body
+func hashBody(req *http.Request) (string, error) {
+	b, err := requestBody(req)
+	if err != nil {
+		return "", err
+	}
+	return hexSHA256(b), nil
+}
+
+// prepareStreaming replaces the body with an aws-chunked encoding of it. The
+// chunks are signed once the seed signature is known.
+func (s *SigV4) prepareStreaming(req *http.Request) error {
+	decoded := req.ContentLength
+	if v := req.Header.Get("X-Amz-Decoded-Content-Length"); v != "" {
+		// signed again, after a redirect or a retry
+		decoded, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if decoded < 0 || (decoded > 0 && req.Body == nil) {
+		return errors.New("go-requests: streaming SigV4 needs the content length")
+	}
+	size := s.ChunkSize
+	if size <= 0 {
+		size = defaultChunkSize
+	}
+	src := req.Body
+	if cr, ok := src.(*awsChunkedReader); ok {
+		src = cr.src
+	}
+	if src == nil {
+		src = http.NoBody
+	}
+	req.Body = &awsChunkedReader{src: src, buf: make([]byte, size)}
+	req.ContentLength = awsChunkedLength(decoded, int64(size))
+	req.Header.Set("X-Amz-Decoded-Content-Length", strconv.FormatInt(decoded, 10))
+	if ce := req.Header.Get("Content-Encoding"); ce == "" {
+		req.Header.Set("Content-Encoding", "aws-chunked")
+	} else if !strings.Contains(ce, "aws-chunked") {
+		req.Header.Set("Content-Encoding", "aws-chunked,"+ce)
+	}
+	return nil
+}
+
+func awsChunkedLength(n, size int64) int64 {
+	chunk := func(k int64) int64 {
+		return int64(len(strconv.FormatInt(k, 16))+chunkSignatureLen+2) + k + 2
+	}
+	total := n / size * chunk(size)
+	if rem := n % size; rem > 0 {
+		total += chunk(rem)
+	}
+	return total + chunk(0)
+}
+
+// awsChunkedReader encodes its source in signed chunks, ending with an empty one
+type awsChunkedReader struct {
+	src io.ReadCloser
+	buf []byte
+	out bytes.Buffer
+	eof bool
+
+	key   []byte
+	date  string
+	scope string
+	prev  string
+}
+
+func (r *awsChunkedReader) Read(p []byte) (int, error) {
+	for r.out.Len() == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(r.src, r.buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		r.writeChunk(r.buf[:n])
+		if n > 0 && err != nil {
+			r.writeChunk(nil)
+		}
+		r.eof = n == 0 || err != nil
+	}
+	return r.out.Read(p)
+}
+
+func (r *awsChunkedReader) writeChunk(data []byte) {
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm + "-PAYLOAD",
+		r.date,
+		r.scope,
+		r.prev,
+		emptyPayloadHash,
+		hexSHA256(data),
+	}, "\n")
+	r.prev = hex.EncodeToString(hmacSHA256(r.key, []byte(stringToSign)))
+	fmt.Fprintf(&r.out, "%x;chunk-signature=%s\r\n", len(data), r.prev)
+	r.out.Write(data)
+	r.out.WriteString("\r\n")
+}
+
+func (r *awsChunkedReader) Close() error {
+	return r.src.Close()
+}
+
+// signs reports whether the header k, in lower case, is signed. Headers which
+// proxies, the transport or a tracer may change are not.
+func (s *SigV4) signs(k string) bool {
+	switch k {
+	case "content-type", "content-md5", "content-encoding", "range":
+		return true
+	}
+	if strings.HasPrefix(k, "x-amz-") {
+		return true
+	}
+	for _, h := range s.SignedHeaders {
+		if strings.EqualFold(h, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SigV4) canonicalHeaders(req *http.Request) (string, string) {
+	values := map[string][]string{}
+	for k, vs := range req.Header {
+		k = strings.ToLower(k)
+		if s.signs(k) {
+			values[k] = append(values[k], vs...)
+		}
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	values["host"] = []string{host}
+	if s.Payload == StreamingPayload {
+		values["content-length"] = []string{strconv.FormatInt(req.ContentLength, 10)}
+	}
+
+	names := make([]string, 0, len(values))
+	for k := range values {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, k := range names {
+		vs := make([]string, len(values[k]))
+		for i, v := range values[k] {
+			vs[i] = strings.Join(strings.Fields(v), " ")
+		}
+		b.WriteString(k + ":" + strings.Join(vs, ",") + "\n")
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+// canonicalURI encodes the path once for S3, and normalizes and encodes the
+// path as sent, which is already encoded once, for other services
+func (s *SigV4) canonicalURI(u *url.URL) string {
+	if s.Service == "s3" {
+		return defaultString(awsURIEncode(u.Path, false), "/")
+	}
+	p := u.EscapedPath()
+	if p == "" {
+		return "/"
+	}
+	clean := path.Clean(p)
+	if strings.HasSuffix(p, "/") && clean != "/" {
+		clean += "/"
+	}
+	return awsURIEncode(clean, false)
+}
+
+func canonicalQuery(u *url.URL) string {
+	q, _ := url.ParseQuery(u.RawQuery)
+	var pairs [][2]string
+	for k, vs := range q {
+		for _, v := range vs {
+			pairs = append(pairs, [2]string{awsURIEncode(k, true), awsURIEncode(v, true)})
+		}
+	}
+	// sorted by the encoded name, then by the value
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	encoded := make([]string, len(pairs))
+	for i, p := range pairs {
+		encoded[i] = p[0] + "=" + p[1]
+	}
+	return strings.Join(encoded, "&")
+}
+
+// awsURIEncode percent-encodes every byte except the unreserved characters of RFC 3986
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
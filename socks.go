@@ -0,0 +1,203 @@
+package requests
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+const (
+	socksVersion        = 5
+	socksAuthNone       = 0x00
+	socksAuthPassword   = 0x02
+	socksNoAcceptable   = 0xff
+	socksCmdConnect     = 0x01
+	socksAddrIPv4       = 0x01
+	socksAddrDomain     = 0x03
+	socksAddrIPv6       = 0x04
+	socksPasswordVer    = 0x01
+	socksReplySucceeded = 0x00
+)
+
+var socksReplies = map[byte]string{
+	0x01: "general SOCKS server failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// socksDialer connects through a SOCKS5 proxy, RFC 1928, with optional
+// username/password authentication, RFC 1929. Host names are resolved locally
+// for socks5:// and by the proxy for socks5h://.
+type socksDialer struct {
+	proxy     string
+	username  string
+	password  string
+	remoteDNS bool
+	dial      func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+func newSOCKSDialer(u *url.URL, dial func(ctx context.Context, network, addr string) (net.Conn, error)) *socksDialer {
+	d := &socksDialer{
+		proxy:     u.Host,
+		remoteDNS: u.Scheme == "socks5h",
+		dial:      dial,
+	}
+	if u.Port() == "" {
+		d.proxy = net.JoinHostPort(u.Hostname(), "1080")
+	}
+	if u.User != nil {
+		d.username = u.User.Username()
+		d.password, _ = u.User.Password()
+	}
+	return d
+}
+
+func (d *socksDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := d.dial(ctx, "tcp", d.proxy)
+	if err != nil {
+		return nil, err
+	}
+	// the handshake is cut short by closing the connection when ctx is done
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	err = d.connect(ctx, conn, addr)
+	if !stop() {
+		err = errors.Join(ctx.Err(), err)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("go-requests: socks5 proxy %s: %w", d.proxy, err)
+	}
+	return conn, nil
+}
+
+func (d *socksDialer) connect(ctx context.Context, conn net.Conn, addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", portStr)
+	}
+
+	methods := []byte{socksAuthNone}
+	if d.username != "" {
+		methods = []byte{socksAuthNone, socksAuthPassword}
+	}
+	if _, err := conn.Write(append([]byte{socksVersion, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != socksVersion {
+		return fmt.Errorf("unexpected SOCKS version %d", reply[0])
+	}
+	switch reply[1] {
+	case socksAuthNone:
+	case socksAuthPassword:
+		if d.username == "" {
+			return errors.New("proxy requires a username and a password")
+		}
+		if err := d.authenticate(conn); err != nil {
+			return err
+		}
+	case socksNoAcceptable:
+		return errors.New("no acceptable authentication methods")
+	default:
+		return fmt.Errorf("unsupported authentication method %d", reply[1])
+	}
+
+	req := []byte{socksVersion, socksCmdConnect, 0}
+	ip := net.ParseIP(host)
+	if ip == nil && !d.remoteDNS {
+		ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+		if err != nil {
+			return err
+		}
+		ip = ips[0]
+		for _, a := range ips {
+			if a.To4() != nil {
+				// IPv4 is preferred, as only one address is tried
+				ip = a
+				break
+			}
+		}
+	}
+	switch {
+	case ip.To4() != nil:
+		req = append(append(req, socksAddrIPv4), ip.To4()...)
+	case ip != nil:
+		req = append(append(req, socksAddrIPv6), ip.To16()...)
+	default:
+		if len(host) > 255 {
+			return fmt.Errorf("host name too long: %s", host)
+		}
+		req = append(append(req, socksAddrDomain, byte(len(host))), host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return err
+	}
+	if head[1] != socksReplySucceeded {
+		if msg, ok := socksReplies[head[1]]; ok {
+			return errors.New(msg)
+		}
+		return fmt.Errorf("unknown reply %d", head[1])
+	}
+	// the bound address is not used
+	var skip int
+	switch head[3] {
+	case socksAddrIPv4:
+		skip = net.IPv4len
+	case socksAddrIPv6:
+		skip = net.IPv6len
+	case socksAddrDomain:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(conn, l); err != nil {
+			return err
+		}
+		skip = int(l[0])
+	default:
+		return fmt.Errorf("unknown address type %d", head[3])
+	}
+	_, err = io.ReadFull(conn, make([]byte, skip+2))
+	return err
+}
+
+func (d *socksDialer) authenticate(conn net.Conn) error {
+	if len(d.username) > 255 || len(d.password) > 255 {
+		return errors.New("username or password too long")
+	}
+	req := []byte{socksPasswordVer, byte(len(d.username))}
+	req = append(req, d.username...)
+	req = append(req, byte(len(d.password)))
+	req = append(req, d.password...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[1] != 0 {
+		return errors.New("username/password authentication failed")
+	}
+	return nil
+}
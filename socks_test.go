@@ -0,0 +1,158 @@
+package requests
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// socksServer is a minimal SOCKS5 server which records the requested addresses
+type socksServer struct {
+	net.Listener
+	username, password string
+
+	mu        sync.Mutex
+	requested []string
+}
+
+func newSOCKSServer(t *testing.T, username, password string) *socksServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	s := &socksServer{Listener: l, username: username, password: password}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *socksServer) serve(conn net.Conn) {
+	defer conn.Close()
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return
+	}
+	methods := make([]byte, head[1])
+	io.ReadFull(conn, methods)
+	if s.username == "" {
+		conn.Write([]byte{5, 0})
+	} else {
+		conn.Write([]byte{5, 2})
+		b := make([]byte, 2)
+		io.ReadFull(conn, b)
+		user := make([]byte, b[1])
+		io.ReadFull(conn, user)
+		io.ReadFull(conn, b[:1])
+		pass := make([]byte, b[0])
+		io.ReadFull(conn, pass)
+		if string(user) != s.username || string(pass) != s.password {
+			conn.Write([]byte{1, 1})
+			return
+		}
+		conn.Write([]byte{1, 0})
+	}
+
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 1, 4:
+		ip := make([]byte, map[byte]int{1: 4, 4: 16}[req[3]])
+		io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case 3:
+		l := make([]byte, 1)
+		io.ReadFull(conn, l)
+		name := make([]byte, l[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	}
+	port := make([]byte, 2)
+	io.ReadFull(conn, port)
+	addr := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+	s.mu.Lock()
+	s.requested = append(s.requested, addr)
+	s.mu.Unlock()
+
+	target, err := net.Dial("tcp", addr)
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+func (s *socksServer) lastRequested() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requested) == 0 {
+		return ""
+	}
+	return s.requested[len(s.requested)-1]
+}
+
+func TestSOCKS5Proxy(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("SOCKS Test"))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+
+	proxy := newSOCKSServer(t, "alice", "s3cret")
+	defer proxy.Close()
+
+	tests := []struct {
+		scheme    string
+		requested string
+	}{
+		{"socks5h", "localhost:" + port},
+		{"socks5", "127.0.0.1:" + port},
+	}
+	for _, tt := range tests {
+		s := NewSession()
+		s.Transport = &Transport{Proxy: tt.scheme + "://alice:s3cret@" + proxy.Addr().String()}
+		resp, err := s.Get("http://localhost:"+port+"/", nil, nil)
+		assert.Nil(t, err, tt.scheme)
+		assert.Equal(t, "SOCKS Test", resp.Text(), tt.scheme)
+		assert.Equal(t, tt.requested, proxy.lastRequested(), tt.scheme)
+		s.Close()
+	}
+}
+
+func TestSOCKS5ProxyErrors(t *testing.T) {
+	proxy := newSOCKSServer(t, "alice", "s3cret")
+	defer proxy.Close()
+
+	s := NewSession()
+	s.Transport = &Transport{Proxy: "socks5h://alice:wrong@" + proxy.Addr().String()}
+	_, err := s.Get("http://localhost:1/", nil, nil)
+	assert.ErrorContains(t, err, "authentication failed")
+
+	s.Transport = &Transport{Proxy: "socks5h://" + proxy.Addr().String()}
+	_, err = s.Get("http://localhost:1/", nil, nil)
+	assert.ErrorContains(t, err, "proxy requires a username and a password")
+
+	open := newSOCKSServer(t, "", "")
+	defer open.Close()
+	s.Transport = &Transport{Proxy: "socks5h://" + open.Addr().String()}
+	_, err = s.Get("http://127.0.0.1:1/", nil, nil)
+	assert.ErrorContains(t, err, "connection refused")
+}
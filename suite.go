@@ -0,0 +1,619 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Suite is a list of API tests kept as data, see LoadSuite
+type Suite struct {
+	Name    string `json:"name"`
+	BaseURL string `json:"base_url"`
+	// Variables are used as {{name}} in the tests, like the values captured
+	// from earlier responses.
+	Variables map[string]string `json:"variables"`
+	// Headers are sent with every request.
+	Headers map[string]string `json:"headers"`
+	Tests   []SuiteTest       `json:"tests"`
+}
+
+// SuiteTest is a request of a Suite and the assertions on its response
+type SuiteTest struct {
+	Name   string `json:"name"`
+	Method string `json:"method"`
+	// URL is relative to the BaseURL of the Suite unless it is absolute.
+	URL     string            `json:"url"`
+	Query   map[string]string `json:"query"`
+	Headers map[string]string `json:"headers"`
+	// The body is one of JSON, Body and Form.
+	JSON       json.RawMessage   `json:"json"`
+	Body       string            `json:"body"`
+	Form       map[string]string `json:"form"`
+	Auth       *Auth             `json:"auth"`
+	Timeout    string            `json:"timeout"`
+	NoRedirect bool              `json:"no_redirect"`
+	Expect     SuiteExpect       `json:"expect"`
+	// Capture saves values of the response as variables for later tests.
+	Capture map[string]SuiteCapture `json:"capture"`
+}
+
+// SuiteExpect are the assertions on a response
+type SuiteExpect struct {
+	Status StatusCodes `json:"status"`
+	// Headers are regular expressions the headers have to match.
+	Headers map[string]string `json:"headers"`
+	// JSON maps paths of the body, like "data.items.0.id", to their expected values.
+	JSON map[string]interface{} `json:"json"`
+	// Body is a regular expression the body has to match.
+	Body string `json:"body"`
+	// MaxTime is the longest the request may take, like "500ms".
+	MaxTime string `json:"max_time"`
+}
+
+// SuiteCapture is where a variable is captured from: a path of the JSON
+// body, a header, or the first group of a regular expression on the body
+type SuiteCapture struct {
+	JSON   string `json:"json"`
+	Header string `json:"header"`
+	Regex  string `json:"regex"`
+}
+
+// StatusCodes is a status code or a list of accepted status codes in JSON
+type StatusCodes []int
+
+func (s *StatusCodes) UnmarshalJSON(b []byte) error {
+	var code int
+	if err := json.Unmarshal(b, &code); err == nil {
+		*s = StatusCodes{code}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]int)(s))
+}
+
+// SuiteResult is the outcome of RunSuite
+type SuiteResult struct {
+	Name     string
+	Started  time.Time
+	Duration time.Duration
+	Tests    []SuiteTestResult
+}
+
+// SuiteTestResult is the outcome of a SuiteTest
+type SuiteTestResult struct {
+	Name     string
+	Duration time.Duration
+	// Failures are the assertions which failed.
+	Failures []string
+	// Err is set if there is no response to check.
+	Err error
+}
+
+// Passed reports whether the request was sent and all assertions held
+func (r SuiteTestResult) Passed() bool {
+	return r.Err == nil && len(r.Failures) == 0
+}
+
+// Failed is the number of tests which did not pass
+func (r *SuiteResult) Failed() int {
+	n := 0
+	for _, t := range r.Tests {
+		if !t.Passed() {
+			n++
+		}
+	}
+	return n
+}
+
+// LoadSuite reads a Suite from a JSON file like
+//
+//	{
+//	  "name": "smoke",
+//	  "base_url": "https://api.example.com",
+//	  "tests": [
+//	    {
+//	      "name": "login",
+//	      "method": "POST",
+//	      "url": "/login",
+//	      "json": {"user": "alice", "password": "{{password}}"},
+//	      "expect": {"status": 200, "json": {"user.name": "alice"}, "max_time": "1s"},
+//	      "capture": {"token": {"json": "token"}}
+//	    },
+//	    {
+//	      "name": "items",
+//	      "url": "/items",
+//	      "headers": {"Authorization": "Bearer {{token}}"},
+//	      "expect": {"status": [200, 204], "headers": {"Content-Type": "^application/json"}}
+//	    }
+//	  ]
+//	}
+func LoadSuite(path string) (*Suite, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	suite := &Suite{}
+	if err := json.Unmarshal(b, suite); err != nil {
+		return nil, fmt.Errorf("go-requests: %s: %w", path, err)
+	}
+	if suite.Name == "" {
+		suite.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return suite, nil
+}
+
+// RunSuite runs suite with the default Session
+func RunSuite(suite *Suite) *SuiteResult {
+	return defaultSession.RunSuite(suite)
+}
+
+// RunSuite runs the tests of suite in order. Cookies are kept between the
+// tests. A test using a variable which a failed test did not capture fails.
+func (s *Session) RunSuite(suite *Suite) *SuiteResult {
+	run := &suiteRun{session: s, suite: suite, vars: map[string]string{}}
+	for k, v := range suite.Variables {
+		run.vars[k] = v
+	}
+	run.jar, _ = cookiejar.New(nil)
+
+	result := &SuiteResult{Name: suite.Name, Started: time.Now()}
+	for i, t := range suite.Tests {
+		name := t.Name
+		if name == "" {
+			name = fmt.Sprintf("%d %s %s", i+1, t.method(), t.URL)
+		}
+		start := time.Now()
+		failures, err := run.test(&t)
+		result.Tests = append(result.Tests, SuiteTestResult{
+			Name:     name,
+			Duration: time.Since(start),
+			Failures: failures,
+			Err:      err,
+		})
+	}
+	result.Duration = time.Since(result.Started)
+	return result
+}
+
+func (t *SuiteTest) method() string {
+	if t.Method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(t.Method)
+}
+
+type suiteRun struct {
+	session *Session
+	suite   *Suite
+	vars    map[string]string
+	jar     *cookiejar.Jar
+}
+
+// expand replaces the {{variables}} of s
+func (r *suiteRun) expand(s string) (string, error) {
+	var err error
+	out := httpFileTemplate.ReplaceAllStringFunc(s, func(m string) string {
+		name := httpFileTemplate.FindStringSubmatch(m)[1]
+		v, ok := r.vars[name]
+		if !ok && err == nil {
+			err = fmt.Errorf("undefined variable {{%s}}", name)
+		}
+		return v
+	})
+	return out, err
+}
+
+// expandValue replaces the variables in the strings of a decoded JSON value
+func (r *suiteRun) expandValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return r.expand(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			e, err := r.expandValue(e)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = e
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			e, err := r.expandValue(e)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = e
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+func (r *suiteRun) expandMap(m map[string]string) (map[string]string, error) {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		v, err := r.expand(v)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = v
+	}
+	return out, nil
+}
+
+func (r *suiteRun) params(t *SuiteTest) (string, *url.Values, *RequestParams, error) {
+	urlStr, err := r.expand(t.URL)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if !strings.Contains(urlStr, "://") {
+		base, err := r.expand(r.suite.BaseURL)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		urlStr = strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(urlStr, "/")
+	}
+
+	p := &RequestParams{Headers: http.Header{}, Cookies: r.jar, AllowRedirects: Redirect().Allow()}
+	if t.NoRedirect {
+		p.AllowRedirects = Redirect().NotAllow()
+	}
+	for _, h := range []map[string]string{r.suite.Headers, t.Headers} {
+		h, err := r.expandMap(h)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		for k, v := range h {
+			p.Headers.Set(k, v)
+		}
+	}
+
+	var qs *url.Values
+	if len(t.Query) > 0 {
+		q, err := r.expandMap(t.Query)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		qs = &url.Values{}
+		for k, v := range q {
+			qs.Set(k, v)
+		}
+	}
+
+	switch {
+	case len(t.JSON) > 0:
+		var v interface{}
+		dec := json.NewDecoder(bytes.NewReader(t.JSON))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return "", nil, nil, err
+		}
+		if p.Json, err = r.expandValue(v); err != nil {
+			return "", nil, nil, err
+		}
+		if p.Headers.Get("Content-Type") == "" {
+			p.Headers.Set("Content-Type", "application/json")
+		}
+	case len(t.Form) > 0:
+		form, err := r.expandMap(t.Form)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		values := url.Values{}
+		for k, v := range form {
+			values.Set(k, v)
+		}
+		p.Data = bytes.NewBufferString(values.Encode())
+		if p.Headers.Get("Content-Type") == "" {
+			p.Headers.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	case t.Body != "":
+		body, err := r.expand(t.Body)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		p.Data = bytes.NewBufferString(body)
+	}
+
+	if t.Auth != nil {
+		user, err := r.expand(t.Auth.Username)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		password, err := r.expand(t.Auth.Password)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		p.Auth = &Auth{Username: user, Password: password}
+	}
+	if t.Timeout != "" {
+		d, err := time.ParseDuration(t.Timeout)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		p.Timeout = &Timeout{Read: d}
+	}
+	return urlStr, qs, p, nil
+}
+
+// test sends the request of t and returns the failed assertions
+func (r *suiteRun) test(t *SuiteTest) ([]string, error) {
+	urlStr, qs, p, err := r.params(t)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.session.send(t.method(), urlStr, qs, p)
+	if err != nil {
+		return nil, err
+	}
+
+	var failures []string
+	fail := func(format string, a ...interface{}) {
+		failures = append(failures, fmt.Sprintf(format, a...))
+	}
+	e := t.Expect
+	if len(e.Status) > 0 {
+		ok := false
+		for _, code := range e.Status {
+			ok = ok || code == resp.StatusCode()
+		}
+		if !ok {
+			fail("status is %d, want %v", resp.StatusCode(), []int(e.Status))
+		}
+	}
+	// in a stable order for the reports
+	for _, k := range sortedKeys(e.Headers) {
+		pattern := e.Headers[k]
+		re, err := r.regexp(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if v := resp.Headers().Get(k); !re.MatchString(v) {
+			fail("header %s is %q, want match of %q", k, v, re)
+		}
+	}
+	for _, path := range sortedKeys(e.JSON) {
+		want, err := r.expandValue(e.JSON[path])
+		if err != nil {
+			return nil, err
+		}
+		got, err := jsonPath(resp.Content(), path)
+		if err != nil {
+			fail("body is not JSON: %v", err)
+			break
+		}
+		if !jsonEqual(got, want) {
+			g, _ := json.Marshal(got)
+			w, _ := json.Marshal(want)
+			fail("json %s is %s, want %s", path, g, w)
+		}
+	}
+	if e.Body != "" {
+		re, err := r.regexp(e.Body)
+		if err != nil {
+			return nil, err
+		}
+		if !re.Match(resp.Content()) {
+			fail("body does not match %q", re)
+		}
+	}
+	if e.MaxTime != "" {
+		max, err := time.ParseDuration(e.MaxTime)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Elapsed() > max {
+			fail("took %s, want at most %s", resp.Elapsed().Round(time.Millisecond), max)
+		}
+	}
+
+	for _, name := range sortedKeys(t.Capture) {
+		v, err := capture(resp, t.Capture[name])
+		if err != nil {
+			fail("capture %s: %v", name, err)
+			continue
+		}
+		r.vars[name] = v
+	}
+	return failures, nil
+}
+
+func (r *suiteRun) regexp(pattern string) (*regexp.Regexp, error) {
+	pattern, err := r.expand(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return regexp.Compile(pattern)
+}
+
+func capture(resp Response, c SuiteCapture) (string, error) {
+	switch {
+	case c.JSON != "":
+		v, err := jsonPath(resp.Content(), c.JSON)
+		if err != nil {
+			return "", err
+		}
+		switch v := v.(type) {
+		case nil:
+			return "", fmt.Errorf("no value at %s", c.JSON)
+		case string:
+			return v, nil
+		case json.Number:
+			return v.String(), nil
+		default:
+			b, err := json.Marshal(v)
+			return string(b), err
+		}
+	case c.Header != "":
+		if v := resp.Headers().Get(c.Header); v != "" {
+			return v, nil
+		}
+		return "", fmt.Errorf("no header %s", c.Header)
+	case c.Regex != "":
+		re, err := regexp.Compile(c.Regex)
+		if err != nil {
+			return "", err
+		}
+		m := re.FindSubmatch(resp.Content())
+		if m == nil {
+			return "", fmt.Errorf("no match of %q", c.Regex)
+		}
+		if len(m) > 1 {
+			return string(m[1]), nil
+		}
+		return string(m[0]), nil
+	}
+	return "", fmt.Errorf("nothing to capture")
+}
+
+// jsonEqual compares JSON values regardless of how their numbers were decoded
+func jsonEqual(a, b interface{}) bool {
+	normalize := func(v interface{}) interface{} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return v
+		}
+		var out interface{}
+		json.Unmarshal(b, &out)
+		return out
+	}
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+// JUnit XML, as read by CI servers
+type (
+	junitSuites struct {
+		XMLName  xml.Name     `xml:"testsuites"`
+		Tests    int          `xml:"tests,attr"`
+		Failures int          `xml:"failures,attr"`
+		Errors   int          `xml:"errors,attr"`
+		Time     string       `xml:"time,attr"`
+		Suites   []junitSuite `xml:"testsuite"`
+	}
+	junitSuite struct {
+		Name      string      `xml:"name,attr"`
+		Tests     int         `xml:"tests,attr"`
+		Failures  int         `xml:"failures,attr"`
+		Errors    int         `xml:"errors,attr"`
+		Time      string      `xml:"time,attr"`
+		Timestamp string      `xml:"timestamp,attr"`
+		Cases     []junitCase `xml:"testcase"`
+	}
+	junitCase struct {
+		Name      string        `xml:"name,attr"`
+		ClassName string        `xml:"classname,attr"`
+		Time      string        `xml:"time,attr"`
+		Failure   *junitMessage `xml:"failure"`
+		Error     *junitMessage `xml:"error"`
+	}
+	junitMessage struct {
+		Message string `xml:"message,attr"`
+		Text    string `xml:",chardata"`
+	}
+)
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// WriteJUnit writes the results of one or more suites as JUnit XML
+func WriteJUnit(w io.Writer, results ...*SuiteResult) error {
+	out := junitSuites{}
+	var total time.Duration
+	for _, r := range results {
+		s := junitSuite{
+			Name:      r.Name,
+			Tests:     len(r.Tests),
+			Time:      junitTime(r.Duration),
+			Timestamp: r.Started.UTC().Format("2006-01-02T15:04:05"),
+		}
+		for _, t := range r.Tests {
+			c := junitCase{Name: t.Name, ClassName: r.Name, Time: junitTime(t.Duration)}
+			switch {
+			case t.Err != nil:
+				c.Error = &junitMessage{Message: t.Err.Error()}
+				s.Errors++
+			case len(t.Failures) > 0:
+				c.Failure = &junitMessage{Message: t.Failures[0], Text: strings.Join(t.Failures, "\n")}
+				s.Failures++
+			}
+			s.Cases = append(s.Cases, c)
+		}
+		out.Suites = append(out.Suites, s)
+		out.Tests += s.Tests
+		out.Failures += s.Failures
+		out.Errors += s.Errors
+		total += r.Duration
+	}
+	out.Time = junitTime(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteTAP writes the results of one or more suites in the Test Anything
+// Protocol, version 13
+func WriteTAP(w io.Writer, results ...*SuiteResult) error {
+	var b strings.Builder
+	n := 0
+	for _, r := range results {
+		n += len(r.Tests)
+	}
+	fmt.Fprintf(&b, "TAP version 13\n1..%d\n", n)
+	i := 0
+	for _, r := range results {
+		for _, t := range r.Tests {
+			i++
+			name := t.Name
+			if len(results) > 1 {
+				name = r.Name + ": " + name
+			}
+			// # starts a directive in TAP
+			name = strings.ReplaceAll(name, "#", `\#`)
+			if t.Passed() {
+				fmt.Fprintf(&b, "ok %d - %s\n", i, name)
+				continue
+			}
+			fmt.Fprintf(&b, "not ok %d - %s\n  ---\n", i, name)
+			if t.Err != nil {
+				fmt.Fprintf(&b, "  error: %s\n", yamlString(t.Err.Error()))
+			} else {
+				b.WriteString("  failures:\n")
+				for _, f := range t.Failures {
+					fmt.Fprintf(&b, "    - %s\n", yamlString(f))
+				}
+			}
+			fmt.Fprintf(&b, "  duration_ms: %d\n  ...\n", t.Duration.Milliseconds())
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// yamlString quotes s as a YAML double quoted string, which is a JSON string
+func yamlString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -0,0 +1,181 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSuite = `{
+  "base_url": "{{base}}",
+  "headers": {"X-Client": "suite"},
+  "tests": [
+    {
+      "name": "login",
+      "method": "post",
+      "url": "/login",
+      "json": {"user": "{{user}}", "id": 12345678901234567890},
+      "expect": {
+        "status": 200,
+        "headers": {"Content-Type": "^application/json"},
+        "json": {"user.name": "{{user}}", "user.roles": ["admin"], "count": 2},
+        "max_time": "5s"
+      },
+      "capture": {"token": {"json": "token"}, "session": {"header": "X-Session"}, "num": {"regex": "\"count\": (\\d+)"}}
+    },
+    {
+      "url": "/items",
+      "query": {"page": "{{num}}"},
+      "headers": {"Authorization": "Bearer {{token}}"},
+      "expect": {"status": [200, 204], "body": "item-{{session}}"}
+    },
+    {
+      "name": "failing",
+      "url": "/items",
+      "expect": {"status": 200, "json": {"missing": 1}, "body": "nope"}
+    },
+    {
+      "name": "undefined",
+      "url": "/{{nothing}}"
+    },
+    {
+      "name": "mismatch",
+      "method": "POST",
+      "url": "/login",
+      "json": {"user": "alice", "id": 12345678901234567890},
+      "expect": {
+        "headers": {"X-Session": "^s2$", "Content-Type": "^text/"},
+        "json": {"user.name": "bob", "count": 3, "user.roles.0": "admin", "token": "x"}
+      }
+    }
+  ]
+}`
+
+func suiteServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Client") != "suite" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/login":
+			var body map[string]interface{}
+			d := json.NewDecoder(r.Body)
+			d.UseNumber()
+			if d.Decode(&body) != nil || body["user"] != "alice" || body["id"] != json.Number("12345678901234567890") {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "1"})
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Session", "s1")
+			w.Write([]byte(`{"token": "t0k3n", "user": {"name": "alice", "roles": ["admin"]}, "count": 2}`))
+		case "/items":
+			if _, err := r.Cookie("sid"); err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.Header.Get("Authorization") != "Bearer t0k3n" || r.URL.Query().Get("page") != "2" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte("item-s1"))
+		}
+	}))
+}
+
+func TestRunSuite(t *testing.T) {
+	ts := suiteServer()
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "smoke.json")
+	os.WriteFile(path, []byte(testSuite), 0o644)
+	suite, err := LoadSuite(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "smoke", suite.Name)
+	assert.Equal(t, StatusCodes{200, 204}, suite.Tests[1].Expect.Status)
+
+	suite.Variables = map[string]string{"base": ts.URL, "user": "alice"}
+	res := RunSuite(suite)
+	assert.Len(t, res.Tests, 5)
+	assert.Equal(t, 3, res.Failed())
+
+	assert.True(t, res.Tests[0].Passed(), res.Tests[0].Failures)
+	assert.Equal(t, "2 GET /items", res.Tests[1].Name)
+	assert.True(t, res.Tests[1].Passed(), res.Tests[1].Failures)
+	assert.Equal(t, []string{
+		"status is 403, want [200]",
+		"body is not JSON: EOF",
+		`body does not match "nope"`,
+	}, res.Tests[2].Failures)
+	assert.EqualError(t, res.Tests[3].Err, "undefined variable {{nothing}}")
+	// in the order of the names, not of the maps
+	assert.Equal(t, []string{
+		`header Content-Type is "application/json", want match of "^text/"`,
+		`header X-Session is "s1", want match of "^s2$"`,
+		"json count is 2, want 3",
+		`json token is "t0k3n", want "x"`,
+		`json user.name is "alice", want "bob"`,
+	}, res.Tests[4].Failures)
+}
+
+func TestWriteReports(t *testing.T) {
+	res := &SuiteResult{
+		Name:     "smoke",
+		Started:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Duration: 1500 * time.Millisecond,
+		Tests: []SuiteTestResult{
+			{Name: "login", Duration: 200 * time.Millisecond},
+			{Name: "items #1", Duration: time.Second, Failures: []string{"status is 500, want [200]", `body does not match "x"`}},
+			{Name: "down", Err: errors.New("connection refused")},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, WriteTAP(buf, res))
+	assert.Equal(t, strings.Join([]string{
+		"TAP version 13",
+		"1..3",
+		"ok 1 - login",
+		`not ok 2 - items \#1`,
+		"  ---",
+		"  failures:",
+		`    - "status is 500, want [200]"`,
+		`    - "body does not match \"x\""`,
+		"  duration_ms: 1000",
+		"  ...",
+		"not ok 3 - down",
+		"  ---",
+		`  error: "connection refused"`,
+		"  duration_ms: 0",
+		"  ...",
+		"",
+	}, "\n"), buf.String())
+
+	buf.Reset()
+	assert.NoError(t, WriteJUnit(buf, res))
+	assert.True(t, strings.HasPrefix(buf.String(), xml.Header))
+	var doc junitSuites
+	assert.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, 3, doc.Tests)
+	assert.Equal(t, 1, doc.Failures)
+	assert.Equal(t, 1, doc.Errors)
+	s := doc.Suites[0]
+	assert.Equal(t, "smoke", s.Name)
+	assert.Equal(t, "2024-01-02T03:04:05", s.Timestamp)
+	assert.Equal(t, "1.500", s.Time)
+	assert.Nil(t, s.Cases[0].Failure)
+	assert.Equal(t, "status is 500, want [200]", s.Cases[1].Failure.Message)
+	assert.Equal(t, "status is 500, want [200]\nbody does not match \"x\"", s.Cases[1].Failure.Text)
+	assert.Equal(t, "connection refused", s.Cases[2].Error.Message)
+}
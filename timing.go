@@ -0,0 +1,114 @@
+package requests
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Timing is the timing of one request sent for a Response: the first one,
+// each redirect and a retry after 401 Unauthorized
+type Timing struct {
+	URL   *url.URL
+	Start time.Time
+	// DNSLookup, TCPConnect and TLSHandshake are 0 for a reused connection.
+	DNSLookup    time.Duration
+	TCPConnect   time.Duration
+	TLSHandshake time.Duration
+	// TimeToFirstByte is from Start to the first byte of the response.
+	TimeToFirstByte time.Duration
+	// ContentTransfer is from the first byte of the response until its body
+	// was read. The bodies of redirects and streamed responses are not read here.
+	ContentTransfer time.Duration
+	// Total is from Start until the body was read, or until the headers
+	// arrived if the body is not read here.
+	Total time.Duration
+	// ConnReused is set if the connection was used before.
+	ConnReused bool
+}
+
+// Elapsed is the time from sending the request until the body of the response
+// was read, including redirects. For a streamed response it ends with the headers.
+func (resp Response) Elapsed() time.Duration { return resp.elapsed }
+
+// Timings is the timing of each request sent, see Timing
+func (resp Response) Timings() []Timing { return resp.timings }
+
+// hopTrace collects the timing of one request with httptrace. Connections
+// may be dialed by other goroutines, so it is locked.
+type hopTrace struct {
+	mu                            sync.Mutex
+	timing                        Timing
+	dnsStart, connStart, tlsStart time.Time
+	firstByte                     time.Time
+}
+
+func newHopTrace(u *url.URL) *hopTrace {
+	d := *displayURL(u)
+	return &hopTrace{timing: Timing{URL: &d, Start: time.Now()}}
+}
+
+// request returns req with the trace in its context
+func (h *hopTrace) request(req *http.Request) *http.Request {
+	lock := func(f func()) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		f()
+	}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			lock(func() { h.dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			lock(func() { h.timing.DNSLookup = time.Since(h.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			lock(func() {
+				// with several addresses, the first attempt starts connecting
+				if h.connStart.IsZero() {
+					h.connStart = time.Now()
+				}
+			})
+		},
+		ConnectDone: func(_, _ string, err error) {
+			lock(func() {
+				if err == nil {
+					h.timing.TCPConnect = time.Since(h.connStart)
+				}
+			})
+		},
+		TLSHandshakeStart: func() {
+			lock(func() { h.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			lock(func() { h.timing.TLSHandshake = time.Since(h.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			lock(func() { h.timing.ConnReused = info.Reused })
+		},
+		GotFirstResponseByte: func() {
+			lock(func() {
+				h.firstByte = time.Now()
+				h.timing.TimeToFirstByte = h.firstByte.Sub(h.timing.Start)
+			})
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+// done returns the timing of a request whose body is read up to now, or not
+// read here if bodyRead is false
+func (h *hopTrace) done(bodyRead bool) Timing {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	t := h.timing
+	t.Total = now.Sub(t.Start)
+	if bodyRead && !h.firstByte.IsZero() {
+		t.ContentTransfer = now.Sub(h.firstByte)
+	}
+	return t
+}
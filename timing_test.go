@@ -0,0 +1,81 @@
+package requests
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimings(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/slow", http.StatusFound)
+		case "/slow":
+			time.Sleep(50 * time.Millisecond)
+			w.Write([]byte("first"))
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+			w.Write([]byte("second"))
+		}
+	}))
+	defer ts.Close()
+
+	s := NewSession()
+	defer s.Close()
+	resp, err := s.Get(ts.URL+"/redirect", nil, &RequestParams{AllowRedirects: Redirect().Allow()})
+	assert.NoError(t, err)
+	assert.Equal(t, "firstsecond", resp.Text())
+
+	timings := resp.Timings()
+	assert.Len(t, timings, 2)
+	assert.Equal(t, ts.URL+"/redirect", timings[0].URL.String())
+	assert.Equal(t, ts.URL+"/slow", timings[1].URL.String())
+	assert.False(t, timings[0].ConnReused)
+	assert.Greater(t, timings[0].TCPConnect, time.Duration(0))
+	assert.Equal(t, time.Duration(0), timings[0].ContentTransfer)
+	assert.True(t, timings[1].ConnReused)
+	assert.Equal(t, time.Duration(0), timings[1].TCPConnect)
+	assert.GreaterOrEqual(t, timings[1].TimeToFirstByte, 50*time.Millisecond)
+	assert.GreaterOrEqual(t, timings[1].ContentTransfer, 50*time.Millisecond)
+	assert.Equal(t, timings[1].Total, timings[1].TimeToFirstByte+timings[1].ContentTransfer)
+	assert.False(t, timings[1].Start.Before(timings[0].Start.Add(timings[0].Total)))
+	assert.GreaterOrEqual(t, resp.Elapsed(), timings[0].Total+timings[1].Total)
+
+	// headers only for a streamed body
+	resp, err = s.Get(ts.URL+"/slow", nil, &RequestParams{Stream: true})
+	assert.NoError(t, err)
+	resp.Body().Close()
+	timings = resp.Timings()
+	assert.Len(t, timings, 1)
+	assert.Equal(t, time.Duration(0), timings[0].ContentTransfer)
+	assert.Less(t, resp.Elapsed(), 100*time.Millisecond)
+}
+
+func TestTimingsTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	s := NewSession()
+	defer s.Close()
+	s.Transport = &Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	resp, err := s.Get(ts.URL, nil, nil)
+	assert.NoError(t, err)
+	timings := resp.Timings()
+	assert.Len(t, timings, 1)
+	assert.Greater(t, timings[0].TLSHandshake, time.Duration(0))
+	assert.Greater(t, timings[0].TimeToFirstByte, timings[0].TLSHandshake)
+}
+
+func TestTimingsDNS(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	resp, err := Get("http://localhost:"+ts.URL[len("http://127.0.0.1:"):], nil, nil)
+	assert.NoError(t, err)
+	assert.Greater(t, resp.Timings()[0].DNSLookup, time.Duration(0))
+}
@@ -0,0 +1,245 @@
+package requests
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	traceparentHeader = "traceparent"
+	tracestateHeader  = "tracestate"
+	traceFlagSampled  = 0x01
+)
+
+var errTraceparent = errors.New("go-requests: invalid traceparent")
+
+// TraceContext is the W3C Trace Context carried by the traceparent and
+// tracestate headers, https://www.w3.org/TR/trace-context/
+type TraceContext struct {
+	TraceID [16]byte
+	// SpanID is the parent-id of traceparent, the span sending the request.
+	SpanID [8]byte
+	Flags  byte
+	// State is the tracestate header, passed on unchanged.
+	State string
+}
+
+// IsValid reports whether the trace and span IDs are set
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID != [16]byte{} && tc.SpanID != [8]byte{}
+}
+
+// Sampled reports whether the sampled flag is set
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&traceFlagSampled != 0
+}
+
+// Traceparent formats the traceparent header, version 00
+func (tc TraceContext) Traceparent() string {
+	return fmt.Sprintf("00-%x-%x-%02x", tc.TraceID, tc.SpanID, tc.Flags)
+}
+
+// ParseTraceparent parses the traceparent and tracestate headers. Versions
+// after 00 are read as far as version 00 goes.
+func ParseTraceparent(traceparent, tracestate string) (TraceContext, error) {
+	var tc TraceContext
+	p := strings.TrimSpace(traceparent)
+	if len(p) < 55 || p[2] != '-' || p[35] != '-' || p[52] != '-' {
+		return tc, errTraceparent
+	}
+	version, err := hex.DecodeString(p[:2])
+	if err != nil || version[0] == 0xff || strings.ToLower(p) != p {
+		return tc, errTraceparent
+	}
+	if (version[0] == 0 && len(p) != 55) || (len(p) > 55 && p[55] != '-') {
+		return tc, errTraceparent
+	}
+	flags, err := hex.DecodeString(p[53:55])
+	if err != nil {
+		return tc, errTraceparent
+	}
+	if _, err := hex.Decode(tc.TraceID[:], []byte(p[3:35])); err != nil {
+		return tc, errTraceparent
+	}
+	if _, err := hex.Decode(tc.SpanID[:], []byte(p[36:52])); err != nil {
+		return tc, errTraceparent
+	}
+	if !tc.IsValid() {
+		return TraceContext{}, errTraceparent
+	}
+	tc.Flags = flags[0]
+	tc.State = strings.TrimSpace(tracestate)
+	return tc, nil
+}
+
+// TraceFromHeader returns the trace context of the headers of a request,
+// like one received by a server
+func TraceFromHeader(h http.Header) (TraceContext, bool) {
+	tc, err := ParseTraceparent(h.Get(traceparentHeader), strings.Join(h.Values(tracestateHeader), ","))
+	return tc, err == nil
+}
+
+type traceKey struct{}
+
+// ContextWithTrace returns a context carrying tc. Requests with the context,
+// as RequestParams.Context, continue the trace.
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceKey{}, tc)
+}
+
+// TraceFromContext returns the trace context carried by ctx
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceKey{}).(TraceContext)
+	return tc, ok && tc.IsValid()
+}
+
+// Span is a request sent by a Session: the first one, each redirect and
+// each retry get a span of their own
+type Span struct {
+	// Name is the request method, like "GET".
+	Name string
+	// Context is the trace context sent in traceparent. Its SpanID is the ID
+	// of this span.
+	Context TraceContext
+	// Parent is the span the request was sent from. It is not valid for a
+	// new trace.
+	Parent TraceContext
+	Start  time.Time
+	End    time.Time
+	// Attributes are named after the OpenTelemetry semantic conventions:
+	// http.request.method, url.full, server.address, server.port,
+	// http.request.resend_count, http.response.status_code and error.type.
+	Attributes map[string]interface{}
+	Err        error
+}
+
+// Tracer receives the spans of a Session, for an adapter to a tracing SDK
+type Tracer interface {
+	// StartSpan is called before the request is sent. It may replace
+	// span.Context, like with the IDs of a span of its own, before the
+	// headers are set from it.
+	StartSpan(ctx context.Context, span *Span)
+	// EndSpan is called when the response arrived, or with span.Err.
+	EndSpan(ctx context.Context, span *Span)
+}
+
+// newSpanID returns a random span ID, which is never all zeros
+func newSpanID() [8]byte {
+	var id [8]byte
+	for id == [8]byte{} {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newTraceID() [16]byte {
+	var id [16]byte
+	for id == [16]byte{} {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// requestTrace is the trace of the requests sent for one call of client.do
+type requestTrace struct {
+	tracer Tracer
+	parent TraceContext
+	root   TraceContext // trace ID of a new trace
+}
+
+// newRequestTrace continues the trace of the context of req. With a Tracer,
+// it continues the trace of a traceparent header of req, or starts a new
+// sampled trace. It returns nil if there is nothing to trace.
+func newRequestTrace(req *http.Request, tracer Tracer) *requestTrace {
+	parent, ok := TraceFromContext(req.Context())
+	if !ok && tracer == nil {
+		return nil
+	}
+	if !ok {
+		parent, ok = TraceFromHeader(req.Header)
+	}
+	t := &requestTrace{tracer: tracer}
+	if ok {
+		t.parent = parent
+		t.root = parent
+	} else {
+		t.root = TraceContext{TraceID: newTraceID(), Flags: traceFlagSampled}
+	}
+	return t
+}
+
+// start sets the trace headers of the n-th request sent
+func (t *requestTrace) start(req *http.Request, n int) *Span {
+	if t == nil {
+		return nil
+	}
+	u := displayURL(req.URL)
+	full := *u
+	full.User = nil
+	span := &Span{
+		Name:    req.Method,
+		Context: TraceContext{TraceID: t.root.TraceID, SpanID: newSpanID(), Flags: t.root.Flags, State: t.root.State},
+		Parent:  t.parent,
+		Start:   time.Now(),
+		Attributes: map[string]interface{}{
+			"http.request.method": req.Method,
+			"url.full":            full.String(),
+			"server.address":      serverAddress(u),
+		},
+	}
+	if port := u.Port(); port != "" {
+		span.Attributes["server.port"] = port
+	}
+	if n > 0 {
+		span.Attributes["http.request.resend_count"] = n
+	}
+	if t.tracer != nil {
+		t.tracer.StartSpan(req.Context(), span)
+	}
+	req.Header.Set(traceparentHeader, span.Context.Traceparent())
+	if span.Context.State != "" {
+		req.Header.Set(tracestateHeader, span.Context.State)
+	} else {
+		req.Header.Del(tracestateHeader)
+	}
+	return span
+}
+
+func serverAddress(u *url.URL) string {
+	if socket, ok := unixSocket(u.Host); ok {
+		return socket
+	}
+	return u.Hostname()
+}
+
+// end finishes span with the status of resp or err
+func (t *requestTrace) end(req *http.Request, span *Span, resp *http.Response, err error) {
+	if t == nil || span == nil {
+		return
+	}
+	span.End = time.Now()
+	if resp != nil {
+		span.Attributes["http.response.status_code"] = resp.StatusCode
+		if resp.StatusCode >= 400 {
+			span.Attributes["error.type"] = fmt.Sprint(resp.StatusCode)
+		}
+	}
+	if err != nil {
+		span.Err = err
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		span.Attributes["error.type"] = fmt.Sprintf("%T", err)
+	}
+	if t.tracer != nil {
+		t.tracer.EndSpan(req.Context(), span)
+	}
+}
@@ -0,0 +1,200 @@
+package requests
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE")
+	assert.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(tc.TraceID[:]))
+	assert.Equal(t, "00f067aa0ba902b7", hex.EncodeToString(tc.SpanID[:]))
+	assert.True(t, tc.Sampled())
+	assert.Equal(t, "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE", tc.State)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", tc.Traceparent())
+
+	// a later version may have more fields
+	tc, err = ParseTraceparent("cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-what-the-future-will-be-like", "")
+	assert.NoError(t, err)
+	assert.False(t, tc.Sampled())
+
+	for _, p := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		_, err := ParseTraceparent(p, "")
+		assert.Error(t, err, p)
+	}
+}
+
+type recordingTracer struct {
+	mu     sync.Mutex
+	spans  []*Span
+	spanID [8]byte
+}
+
+func (r *recordingTracer) StartSpan(ctx context.Context, span *Span) {
+	if r.spanID != [8]byte{} {
+		span.Context.SpanID = r.spanID
+	}
+}
+
+func (r *recordingTracer) EndSpan(ctx context.Context, span *Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func TestTracePropagation(t *testing.T) {
+	var received []http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Clone())
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/done", http.StatusFound)
+		}
+	}))
+	defer ts.Close()
+
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "rojo=00f067aa0ba902b7")
+	ctx := ContextWithTrace(context.Background(), parent)
+
+	s := NewSession()
+	defer s.Close()
+	_, err := s.Get(ts.URL+"/redirect", nil, &RequestParams{Context: ctx, AllowRedirects: Redirect().Allow()})
+	assert.NoError(t, err)
+	assert.Len(t, received, 2)
+	var spanIDs []string
+	for _, h := range received {
+		tc, ok := TraceFromHeader(h)
+		assert.True(t, ok)
+		assert.Equal(t, parent.TraceID, tc.TraceID)
+		assert.NotEqual(t, parent.SpanID, tc.SpanID)
+		assert.True(t, tc.Sampled())
+		assert.Equal(t, "rojo=00f067aa0ba902b7", h.Get("tracestate"))
+		spanIDs = append(spanIDs, hex.EncodeToString(tc.SpanID[:]))
+	}
+	assert.NotEqual(t, spanIDs[0], spanIDs[1])
+
+	// nothing is added without a trace or a Tracer
+	received = nil
+	_, err = s.Get(ts.URL+"/done", nil, &RequestParams{Headers: http.Header{"Traceparent": {"kept"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "kept", received[0].Get("traceparent"))
+}
+
+func TestTracer(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/auth", http.StatusFound)
+		case "/auth":
+			attempts++
+			if attempts == 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+	defer ts.Close()
+
+	tracer := &recordingTracer{}
+	s := NewSession()
+	defer s.Close()
+	s.Tracer = tracer
+	s.Auth = &retryingAuth{}
+	_, err := s.Get(ts.URL+"/redirect", nil, &RequestParams{AllowRedirects: Redirect().Allow()})
+	assert.NoError(t, err)
+
+	spans := tracer.spans
+	assert.Len(t, spans, 3)
+	assert.False(t, spans[0].Parent.IsValid())
+	for i, span := range spans {
+		assert.Equal(t, "GET", span.Name)
+		assert.Equal(t, spans[0].Context.TraceID, span.Context.TraceID)
+		assert.True(t, span.Context.Sampled())
+		assert.False(t, span.End.Before(span.Start))
+		if i > 0 {
+			assert.Equal(t, i, span.Attributes["http.request.resend_count"])
+			assert.NotEqual(t, spans[i-1].Context.SpanID, span.Context.SpanID)
+		}
+	}
+	assert.Equal(t, ts.URL+"/redirect", spans[0].Attributes["url.full"])
+	assert.Equal(t, "127.0.0.1", spans[0].Attributes["server.address"])
+	assert.Equal(t, http.StatusFound, spans[0].Attributes["http.response.status_code"])
+	assert.Equal(t, http.StatusUnauthorized, spans[1].Attributes["http.response.status_code"])
+	assert.Equal(t, "401", spans[1].Attributes["error.type"])
+	assert.Equal(t, http.StatusOK, spans[2].Attributes["http.response.status_code"])
+
+	// the Tracer may use IDs of its own
+	var got string
+	own := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("traceparent")
+	}))
+	defer own.Close()
+	tracer.spanID = [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+	_, err = s.Get(own.URL, nil, nil)
+	assert.NoError(t, err)
+	assert.Regexp(t, "^00-[0-9a-f]{32}-0102030405060708-01$", got)
+
+	// failed requests end with the error
+	tracer.spans = nil
+	ts.Close()
+	_, err = s.Get(ts.URL, nil, nil)
+	assert.Error(t, err)
+	assert.Len(t, tracer.spans, 1)
+	assert.Error(t, tracer.spans[0].Err)
+	assert.Equal(t, "*net.OpError", tracer.spans[0].Attributes["error.type"])
+}
+
+// retryingAuth asks for one retry after 401 Unauthorized
+type retryingAuth struct{}
+
+func (a *retryingAuth) Authenticate(req *http.Request) error { return nil }
+
+func (a *retryingAuth) Unauthorized(req *http.Request, resp *http.Response) bool { return true }
+
+// headerAuth records the traceparent header each request is authenticated with
+type headerAuth struct{ traceparents []string }
+
+func (a *headerAuth) Authenticate(req *http.Request) error {
+	a.traceparents = append(a.traceparents, req.Header.Get("traceparent"))
+	return nil
+}
+
+func TestTraceBeforeAuth(t *testing.T) {
+	var received []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get("traceparent"))
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/done", http.StatusFound)
+		}
+	}))
+	defer ts.Close()
+
+	auth := &headerAuth{}
+	s := NewSession()
+	defer s.Close()
+	s.Auth = auth
+	s.Tracer = &recordingTracer{}
+	_, err := s.Get(ts.URL+"/redirect", nil, &RequestParams{
+		Headers:        http.Header{"Traceparent": {"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}},
+		AllowRedirects: Redirect().Allow(),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, received, 2)
+	// requests are signed with the headers they are sent with
+	assert.Equal(t, received, auth.traceparents)
+}
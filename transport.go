@@ -0,0 +1,191 @@
+package requests
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	defaultDialTimeout = 30 * time.Second
+	defaultKeepAlive   = 30 * time.Second
+)
+
+// Transport tunes how a Session connects to servers. Zero values keep the
+// defaults of http.DefaultTransport.
+type Transport struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+	DisableKeepAlives   bool
+	DisableHTTP2        bool
+	// Proxy is the URL of the proxy server, http://, https://, socks5:// or
+	// socks5h:// (host names resolved by the proxy) with optional user:password.
+	// Empty uses HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+	Proxy string
+	// TLSClientConfig configures TLS, e.g. client certificates or InsecureSkipVerify.
+	TLSClientConfig *tls.Config
+	// DialContext replaces the dialer for TCP connections.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	// LocalAddr binds outgoing connections to this local IP address.
+	LocalAddr string
+	// Interface binds outgoing connections to the first address of this network interface.
+	Interface string
+	// RoundTripper, if set, is used as is and the options above are ignored.
+	// http+unix URLs do not work with it.
+	RoundTripper http.RoundTripper
+}
+
+func (t *Transport) roundTripper() http.RoundTripper {
+	if t.RoundTripper != nil {
+		return t.RoundTripper
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.DialContext = t.dialContext()
+	if t.Proxy != "" {
+		proxy, err := url.Parse(t.Proxy)
+		if err == nil && (proxy.Scheme == "socks5" || proxy.Scheme == "socks5h") {
+			dial := tr.DialContext
+			socks := newSOCKSDialer(proxy, dial)
+			tr.Proxy = nil
+			tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				if _, ok := unixSocket(addr); ok {
+					return dial(ctx, network, addr)
+				}
+				return socks.DialContext(ctx, network, addr)
+			}
+		} else {
+			tr.Proxy = func(*http.Request) (*url.URL, error) { return proxy, err }
+		}
+	}
+	if proxy := tr.Proxy; proxy != nil {
+		// http+unix requests go to the socket, never to a proxy
+		tr.Proxy = func(req *http.Request) (*url.URL, error) {
+			if _, ok := unixSocket(req.URL.Host); ok {
+				return nil, nil
+			}
+			return proxy(req)
+		}
+	}
+	if t.TLSClientConfig != nil {
+		tr.TLSClientConfig = t.TLSClientConfig.Clone()
+	}
+	if t.MaxIdleConns != 0 {
+		tr.MaxIdleConns = t.MaxIdleConns
+	}
+	if t.MaxIdleConnsPerHost != 0 {
+		tr.MaxIdleConnsPerHost = t.MaxIdleConnsPerHost
+	}
+	if t.MaxConnsPerHost != 0 {
+		tr.MaxConnsPerHost = t.MaxConnsPerHost
+	}
+	if t.IdleConnTimeout != 0 {
+		tr.IdleConnTimeout = t.IdleConnTimeout
+	}
+	tr.DisableKeepAlives = t.DisableKeepAlives
+	if t.DisableHTTP2 {
+		// a non-nil empty map turns HTTP/2 off
+		tr.ForceAttemptHTTP2 = false
+		tr.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return tr
+}
+
+func (t *Transport) dialContext() func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if socket, ok := unixSocket(addr); ok {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+		if t.DialContext != nil {
+			return t.DialContext(ctx, network, addr)
+		}
+		d := &net.Dialer{
+			Timeout:   defaultDialTimeout,
+			KeepAlive: defaultKeepAlive,
+		}
+		local, err := t.localAddr()
+		if err != nil {
+			return nil, err
+		}
+		if local != nil {
+			d.LocalAddr = &net.TCPAddr{IP: local}
+		}
+		return d.DialContext(ctx, network, addr)
+	}
+}
+
+// localAddr returns the IP address outgoing connections are bound to
+func (t *Transport) localAddr() (net.IP, error) {
+	if t.LocalAddr != "" {
+		ip := net.ParseIP(t.LocalAddr)
+		if ip == nil {
+			return nil, errors.New("go-requests: invalid local address " + t.LocalAddr)
+		}
+		return ip, nil
+	}
+	if t.Interface == "" {
+		return nil, nil
+	}
+	iface, err := net.InterfaceByName(t.Interface)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	var found net.IP
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ipnet.IP.To4() != nil {
+			return ipnet.IP, nil
+		}
+		if found == nil {
+			found = ipnet.IP
+		}
+	}
+	if found == nil {
+		return nil, errors.New("go-requests: no address on interface " + t.Interface)
+	}
+	return found, nil
+}
+
+// defaultTransport is used by Sessions without Transport
+var defaultTransport = (&Transport{}).roundTripper()
+
+// transport returns the round tripper for t, building it only when t has changed
+// so that connections are pooled across requests.
+func (c *client) transport(t *Transport) http.RoundTripper {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t == c.transportFrom && c.roundTripper != nil {
+		return c.roundTripper
+	}
+	if tr, ok := c.roundTripper.(*http.Transport); ok && c.built {
+		tr.CloseIdleConnections()
+	}
+	c.transportFrom, c.roundTripper, c.built = t, defaultTransport, false
+	if t != nil {
+		c.roundTripper, c.built = t.roundTripper(), t.RoundTripper == nil
+	}
+	return c.roundTripper
+}
+
+// closeIdleConnections closes the idle connections of a transport the client
+// built, not of defaultTransport or a RoundTripper of the caller
+func (c *client) closeIdleConnections() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if tr, ok := c.roundTripper.(*http.Transport); ok && c.built {
+		tr.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,97 @@
+package requests
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingRoundTripper struct {
+	count int
+}
+
+func (rt *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.count++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestTransportRoundTripper(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Transport Test"))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	rt := &countingRoundTripper{}
+	s := NewSession()
+	s.Transport = &Transport{RoundTripper: rt}
+	defer s.Close()
+
+	resp, err := s.Get(ts.URL, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "Transport Test", resp.Text())
+	assert.Equal(t, 1, rt.count)
+}
+
+func TestTransportOptions(t *testing.T) {
+	tr := (&Transport{
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 5,
+		MaxConnsPerHost:     20,
+		IdleConnTimeout:     time.Second,
+		DisableKeepAlives:   true,
+		DisableHTTP2:        true,
+	}).roundTripper().(*http.Transport)
+
+	assert.Equal(t, 10, tr.MaxIdleConns)
+	assert.Equal(t, 5, tr.MaxIdleConnsPerHost)
+	assert.Equal(t, 20, tr.MaxConnsPerHost)
+	assert.Equal(t, time.Second, tr.IdleConnTimeout)
+	assert.True(t, tr.DisableKeepAlives)
+	assert.False(t, tr.ForceAttemptHTTP2)
+	assert.NotNil(t, tr.TLSNextProto)
+}
+
+func TestTransportReused(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	s := NewSession()
+	s.Transport = &Transport{MaxIdleConnsPerHost: 1}
+	defer s.Close()
+
+	s.Get(ts.URL, nil, nil)
+	first := s.client.roundTripper
+	s.Get(ts.URL, nil, nil)
+	assert.True(t, first == s.client.roundTripper, "transport should be reused")
+}
+
+func TestSessionCloseSharedTransport(t *testing.T) {
+	var conns atomic.Int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	s := NewSession()
+	_, err := s.Get(ts.URL, nil, nil)
+	assert.Nil(t, err)
+
+	// another Session without Transport leaves the shared connections alone
+	assert.Nil(t, NewSession().Close())
+	_, err = s.Get(ts.URL, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), conns.Load())
+}
@@ -0,0 +1,69 @@
+package requests
+
+import (
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// http+unix://%2Fvar%2Frun%2Fdocker.sock/info sends requests over the unix
+// domain socket /var/run/docker.sock, like Python's requests-unixsocket.
+const (
+	unixScheme = "http+unix"
+	// the socket path is carried hex encoded in URL.Host so that the transport
+	// pools connections per socket
+	unixHostSuffix = ".go-requests-unix"
+	unixHostHeader = "localhost"
+)
+
+func parseURL(urlStr string) (*url.URL, error) {
+	if !strings.HasPrefix(urlStr, unixScheme+"://") {
+		return url.Parse(urlStr)
+	}
+	rest := strings.TrimPrefix(urlStr, unixScheme+"://")
+	i := strings.IndexAny(rest, "/?#")
+	if i < 0 {
+		i = len(rest)
+	}
+	socket, err := url.PathUnescape(rest[:i])
+	if err != nil {
+		return nil, err
+	}
+	if socket == "" {
+		return nil, errors.New("go-requests: missing unix socket path in " + urlStr)
+	}
+	u, err := url.Parse("http://" + unixHostHeader + rest[i:])
+	if err != nil {
+		return nil, err
+	}
+	u.Host = hex.EncodeToString([]byte(socket)) + unixHostSuffix
+	return u, nil
+}
+
+// unixSocket returns the socket path of a host made by parseURL
+func unixSocket(host string) (string, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if !strings.HasSuffix(host, unixHostSuffix) {
+		return "", false
+	}
+	b, err := hex.DecodeString(strings.TrimSuffix(host, unixHostSuffix))
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// displayURL turns a URL made by parseURL back into its http+unix form
+func displayURL(u *url.URL) *url.URL {
+	socket, ok := unixSocket(u.Host)
+	if !ok {
+		return u
+	}
+	d := *u
+	d.Scheme, d.Host = unixScheme, socket
+	return &d
+}
@@ -0,0 +1,120 @@
+package requests
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "go-requests")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "test.sock")
+
+	l, err := net.Listen("unix", socket)
+	assert.Nil(t, err)
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + " " + r.URL.RequestURI()))
+	})
+	go http.Serve(l, handler)
+	defer l.Close()
+
+	resp, err := Get("http+unix://"+url.PathEscape(socket)+"/v1.41/info", &url.Values{"a": {"b"}}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode())
+	assert.Equal(t, "localhost /v1.41/info?a=b", resp.Text())
+	assert.Equal(t, "http+unix", resp.Url().Scheme)
+	assert.Equal(t, socket, resp.Url().Host)
+}
+
+func TestUnixSocketProxy(t *testing.T) {
+	dir, err := os.MkdirTemp("", "go-requests")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "test.sock")
+
+	l, err := net.Listen("unix", socket)
+	assert.Nil(t, err)
+	go http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("socket"))
+	}))
+	defer l.Close()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("proxy"))
+	}))
+	defer proxy.Close()
+
+	s := NewSession()
+	defer s.Close()
+	s.Transport = &Transport{Proxy: proxy.URL}
+	resp, err := s.Get("http+unix://"+url.PathEscape(socket)+"/info", nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "socket", resp.Text())
+}
+
+func TestParseURL(t *testing.T) {
+	u, err := parseURL("http+unix://%2Fvar%2Frun%2Fdocker.sock/info?all=1")
+	assert.Nil(t, err)
+	assert.Equal(t, "http", u.Scheme)
+	assert.Equal(t, "/info", u.Path)
+	assert.Equal(t, "all=1", u.RawQuery)
+	socket, ok := unixSocket(u.Host)
+	assert.True(t, ok)
+	assert.Equal(t, "/var/run/docker.sock", socket)
+	assert.Equal(t, "http+unix://%2Fvar%2Frun%2Fdocker.sock/info?all=1", displayURL(u).String())
+
+	_, err = parseURL("http+unix:///info")
+	assert.NotNil(t, err)
+}
+
+func TestTransportDialContext(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	var dialed string
+	s := NewSession()
+	s.Transport = &Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed = addr
+			return (&net.Dialer{}).DialContext(ctx, network, ts.Listener.Addr().String())
+		},
+	}
+	defer s.Close()
+
+	resp, err := s.Get("http://example.invalid/", nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode())
+	assert.Equal(t, "example.invalid:80", dialed)
+}
+
+func TestTransportLocalAddr(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	s := NewSession()
+	s.Transport = &Transport{LocalAddr: "127.0.0.1"}
+	defer s.Close()
+
+	resp, err := s.Get(ts.URL, nil, nil)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(resp.Text(), "127.0.0.1:"))
+
+	s.Transport = &Transport{LocalAddr: "not-an-ip"}
+	_, err = s.Get(ts.URL, nil, nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,419 @@
+package requests
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MessageType is the type of a WebSocket message
+type MessageType int
+
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// WebSocket close codes, RFC 6455 7.4.1
+const (
+	CloseNormalClosure   = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseNoStatus        = 1005
+	CloseMessageTooBig   = 1009
+	defaultWebSocketRead = 32 << 20
+	webSocketCloseWait   = 5 * time.Second
+	webSocketGUID        = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// CloseError is returned by ReadMessage when the peer closed the connection
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("go-requests: websocket closed: %d %s", e.Code, e.Text)
+}
+
+// WebSocketConn is a client WebSocket connection. ReadMessage must not be
+// called concurrently; writes may be.
+type WebSocketConn struct {
+	// ReadLimit is the maximum size of a message. Defaults to 32MB.
+	ReadLimit int64
+	// OnPong is called when a pong is received. Pings are answered automatically.
+	OnPong func(data []byte)
+
+	resp Response
+	rwc  io.ReadWriteCloser
+	br   *bufio.Reader
+
+	readMu  sync.Mutex
+	writeMu sync.Mutex
+
+	closeOnce     sync.Once
+	closeSent     bool
+	closeReceived chan struct{}
+}
+
+// WebSocket opens a WebSocket connection with given urlStr, queryString and RequestParams
+func WebSocket(urlStr string, queryString *url.Values, r *RequestParams) (*WebSocketConn, error) {
+	return defaultSession.WebSocket(urlStr, queryString, r)
+}
+
+// WebSocket opens a WebSocket connection with given urlStr, queryString and RequestParams.
+// urlStr is a ws:// or wss:// URL. The opening handshake is sent like any other
+// request of the Session, so headers, cookies, auth, proxy and TLS settings apply.
+// Timeout.Read is not applied to the connection.
+func (s *Session) WebSocket(urlStr string, queryString *url.Values, r *RequestParams) (*WebSocketConn, error) {
+	switch {
+	case strings.HasPrefix(urlStr, "ws://"):
+		urlStr = "http://" + strings.TrimPrefix(urlStr, "ws://")
+	case strings.HasPrefix(urlStr, "wss://"):
+		urlStr = "https://" + strings.TrimPrefix(urlStr, "wss://")
+	}
+
+	var params RequestParams
+	if r != nil {
+		params = *r
+	}
+	params.Stream = true
+	params.Data, params.Json = nil, nil
+	if r != nil && r.Timeout != nil {
+		params.Timeout = &Timeout{Connect: r.Timeout.Connect}
+	}
+	params.Headers = make(http.Header)
+	if r != nil && r.Headers != nil {
+		params.Headers = r.Headers.Clone()
+	}
+	key, err := webSocketKey()
+	if err != nil {
+		return nil, err
+	}
+	params.Headers.Set("Connection", "Upgrade")
+	params.Headers.Set("Upgrade", "websocket")
+	params.Headers.Set("Sec-WebSocket-Version", "13")
+	params.Headers.Set("Sec-WebSocket-Key", key)
+
+	resp, err := s.Get(urlStr, queryString, &params)
+	if err != nil {
+		return nil, err
+	}
+	body := resp.Body()
+	if resp.StatusCode() != http.StatusSwitchingProtocols {
+		body.Close()
+		return nil, fmt.Errorf("go-requests: websocket handshake returned %s", resp.Status())
+	}
+	if !strings.EqualFold(resp.Headers().Get("Upgrade"), "websocket") ||
+		resp.Headers().Get("Sec-WebSocket-Accept") != webSocketAccept(key) {
+		body.Close()
+		return nil, errors.New("go-requests: invalid websocket handshake response")
+	}
+	// the streamed body is the connection, wrapped to release the request context
+	var rwc io.ReadWriteCloser
+	cc, ok := body.(*cancelCloser)
+	if ok {
+		rwc, ok = cc.ReadCloser.(io.ReadWriteCloser)
+	}
+	if !ok {
+		body.Close()
+		return nil, errors.New("go-requests: websocket connection is not writable")
+	}
+	return &WebSocketConn{
+		ReadLimit:     defaultWebSocketRead,
+		resp:          resp,
+		rwc:           &webSocketCloser{ReadWriteCloser: rwc, body: body},
+		br:            bufio.NewReader(rwc),
+		closeReceived: make(chan struct{}),
+	}, nil
+}
+
+// webSocketCloser also releases the context of the handshake request
+type webSocketCloser struct {
+	io.ReadWriteCloser
+	body io.Closer
+}
+
+func (c *webSocketCloser) Close() error { return c.body.Close() }
+
+func webSocketKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func webSocketAccept(key string) string {
+	h := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// Response returns the handshake response
+func (c *WebSocketConn) Response() Response { return c.resp }
+
+// ReadMessage reads the next text or binary message. Control frames are handled
+// while reading. If ctx is done before a message arrives, the connection is closed.
+func (c *WebSocketConn) ReadMessage(ctx context.Context) (MessageType, []byte, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	stop := context.AfterFunc(ctx, func() { c.rwc.Close() })
+	defer stop()
+
+	mt, data, err := c.readMessage()
+	if err != nil && ctx.Err() != nil {
+		return 0, nil, ctx.Err()
+	}
+	return mt, data, err
+}
+
+func (c *WebSocketConn) readMessage() (MessageType, []byte, error) {
+	var (
+		mt      MessageType
+		message []byte
+	)
+	for {
+		f, err := readFrame(c.br, c.ReadLimit-int64(len(message)))
+		if err == errFrameTooBig {
+			c.writeClose(CloseMessageTooBig, "")
+			c.rwc.Close()
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		switch f.opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, f.payload); err != nil {
+				return 0, nil, err
+			}
+		case opPong:
+			if c.OnPong != nil {
+				c.OnPong(f.payload)
+			}
+		case opClose:
+			return 0, nil, c.receivedClose(f.payload)
+		case opText, opBinary:
+			if mt != 0 {
+				return 0, nil, c.protocolError("new message before the previous one was finished")
+			}
+			mt, message = MessageType(f.opcode), f.payload
+			if f.fin {
+				return mt, message, nil
+			}
+		case opContinuation:
+			if mt == 0 {
+				return 0, nil, c.protocolError("continuation frame without a message")
+			}
+			message = append(message, f.payload...)
+			if f.fin {
+				return mt, message, nil
+			}
+		default:
+			return 0, nil, c.protocolError(fmt.Sprintf("unknown opcode %d", f.opcode))
+		}
+	}
+}
+
+func (c *WebSocketConn) receivedClose(payload []byte) error {
+	ce := &CloseError{Code: CloseNoStatus}
+	if len(payload) >= 2 {
+		ce.Code = int(binary.BigEndian.Uint16(payload))
+		ce.Text = string(payload[2:])
+	}
+	c.writeMu.Lock()
+	sent := c.closeSent
+	c.writeMu.Unlock()
+	if !sent {
+		// echo the close frame to complete the closing handshake
+		c.writeClose(ce.Code, "")
+	}
+	c.closeOnce.Do(func() { close(c.closeReceived) })
+	c.rwc.Close()
+	return ce
+}
+
+func (c *WebSocketConn) protocolError(msg string) error {
+	c.writeClose(CloseProtocolError, "")
+	c.rwc.Close()
+	return errors.New("go-requests: websocket protocol error: " + msg)
+}
+
+// WriteMessage sends data as a text or binary message
+func (c *WebSocketConn) WriteMessage(mt MessageType, data []byte) error {
+	if mt != TextMessage && mt != BinaryMessage {
+		return fmt.Errorf("go-requests: invalid websocket message type %d", mt)
+	}
+	return c.writeFrame(byte(mt), data)
+}
+
+// Ping sends a ping. The pong is passed to OnPong while reading.
+func (c *WebSocketConn) Ping(data []byte) error {
+	return c.writeFrame(opPing, data)
+}
+
+// Close sends a close frame with code and reason, waits for the peer to
+// answer it and closes the connection.
+func (c *WebSocketConn) Close(code int, reason string) error {
+	err := c.writeClose(code, reason)
+	if c.readMu.TryLock() {
+		// nobody is reading, so read until the close frame comes back
+		timer := time.AfterFunc(webSocketCloseWait, func() { c.rwc.Close() })
+		for {
+			f, rerr := readFrame(c.br, c.ReadLimit)
+			if rerr != nil || f.opcode == opClose {
+				break
+			}
+		}
+		timer.Stop()
+		c.readMu.Unlock()
+	} else {
+		select {
+		case <-c.closeReceived:
+		case <-time.After(webSocketCloseWait):
+		}
+	}
+	c.rwc.Close()
+	return err
+}
+
+func (c *WebSocketConn) writeClose(code int, reason string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return nil
+	}
+	c.closeSent = true
+	var payload []byte
+	if code != CloseNoStatus {
+		payload = make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, reason...)
+	}
+	return writeFrame(c.rwc, frame{fin: true, opcode: opClose, payload: payload}, true)
+}
+
+func (c *WebSocketConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return errors.New("go-requests: websocket is closing")
+	}
+	return writeFrame(c.rwc, frame{fin: true, opcode: opcode, payload: payload}, true)
+}
+
+type frame struct {
+	fin     bool
+	opcode  byte
+	payload []byte
+}
+
+var errFrameTooBig = errors.New("go-requests: websocket message too big")
+
+// readFrame reads a frame, RFC 6455 5.2, unmasking the payload if needed
+func readFrame(r io.Reader, limit int64) (frame, error) {
+	var h [2]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return frame{}, err
+	}
+	f := frame{fin: h[0]&0x80 != 0, opcode: h[0] & 0x0f}
+	masked := h[1]&0x80 != 0
+	length := uint64(h[1] & 0x7f)
+	switch length {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return frame{}, err
+		}
+		length = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return frame{}, err
+		}
+		length = binary.BigEndian.Uint64(b[:])
+	}
+	if f.opcode >= opClose && (length > 125 || !f.fin) {
+		return frame{}, errors.New("go-requests: websocket protocol error: invalid control frame")
+	}
+	if limit >= 0 && length > uint64(limit) {
+		return frame{}, errFrameTooBig
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return frame{}, err
+		}
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return frame{}, err
+	}
+	if masked {
+		maskBytes(mask, f.payload)
+	}
+	return f, nil
+}
+
+// writeFrame writes f as a single frame. Frames sent by a client must be masked.
+func writeFrame(w io.Writer, f frame, masked bool) error {
+	buf := make([]byte, 0, 14+len(f.payload))
+	b0 := f.opcode
+	if f.fin {
+		b0 |= 0x80
+	}
+	buf = append(buf, b0)
+	var maskBit byte
+	if masked {
+		maskBit = 0x80
+	}
+	switch n := len(f.payload); {
+	case n <= 125:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, maskBit|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, maskBit|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	payload := f.payload
+	if masked {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		buf = append(buf, mask[:]...)
+		payload = append([]byte(nil), payload...)
+		maskBytes(mask, payload)
+	}
+	buf = append(buf, payload...)
+	_, err := w.Write(buf)
+	return err
+}
+
+func maskBytes(mask [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= mask[i%4]
+	}
+}
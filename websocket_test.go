@@ -0,0 +1,173 @@
+package requests
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// echoWebSocket echoes messages back until it receives a close frame
+func echoWebSocket(t *testing.T) *httptest.Server {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		assert.Nil(t, err)
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+		brw.WriteString("Upgrade: websocket\r\nConnection: Upgrade\r\n")
+		brw.WriteString("Set-Cookie: session=ws\r\n")
+		brw.WriteString("Sec-WebSocket-Accept: " + webSocketAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		brw.Flush()
+
+		br := bufio.NewReader(conn)
+		for {
+			f, err := readFrame(br, -1)
+			if err != nil {
+				return
+			}
+			switch f.opcode {
+			case opPing:
+				writeFrame(conn, frame{fin: true, opcode: opPong, payload: f.payload}, false)
+			case opClose:
+				writeFrame(conn, f, false)
+				return
+			case opText:
+				if string(f.payload) == "ping me" {
+					writeFrame(conn, frame{fin: true, opcode: opPing, payload: []byte("p")}, false)
+				}
+				if string(f.payload) == "bye" {
+					writeFrame(conn, frame{fin: true, opcode: opClose, payload: []byte{0x03, 0xe9, 'b', 'y', 'e'}}, false)
+					continue
+				}
+				// echo as two fragments
+				half := len(f.payload) / 2
+				writeFrame(conn, frame{opcode: opText, payload: f.payload[:half]}, false)
+				writeFrame(conn, frame{fin: true, opcode: opContinuation, payload: f.payload[half:]}, false)
+			default:
+				writeFrame(conn, f, false)
+			}
+		}
+	})
+	return httptest.NewServer(handler)
+}
+
+func TestWebSocket(t *testing.T) {
+	ts := echoWebSocket(t)
+	defer ts.Close()
+
+	conn, err := WebSocket("ws"+strings.TrimPrefix(ts.URL, "http"), nil, &RequestParams{
+		Auth: &Auth{Username: "user", Password: "pass"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 101, conn.Response().StatusCode())
+	assert.Equal(t, "session", conn.Response().Cookies()[0].Name)
+
+	ctx := context.Background()
+	assert.Nil(t, conn.WriteMessage(TextMessage, []byte("hello websocket")))
+	mt, data, err := conn.ReadMessage(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, TextMessage, mt)
+	assert.Equal(t, "hello websocket", string(data))
+
+	big := make([]byte, 70000)
+	assert.Nil(t, conn.WriteMessage(BinaryMessage, big))
+	mt, data, err = conn.ReadMessage(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, BinaryMessage, mt)
+	assert.Equal(t, len(big), len(data))
+
+	var pong string
+	conn.OnPong = func(data []byte) { pong = string(data) }
+	assert.Nil(t, conn.Ping([]byte("are you there")))
+	assert.Nil(t, conn.WriteMessage(TextMessage, []byte("ping me")))
+	_, data, err = conn.ReadMessage(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "ping me", string(data))
+	assert.Equal(t, "are you there", pong)
+
+	assert.Nil(t, conn.Close(CloseNormalClosure, "done"))
+}
+
+func TestWebSocketDownloadProgress(t *testing.T) {
+	ts := echoWebSocket(t)
+	defer ts.Close()
+
+	var transferred int64
+	conn, err := WebSocket("ws"+strings.TrimPrefix(ts.URL, "http"), nil, &RequestParams{
+		DownloadProgress: func(p Progress) { transferred = p.Transferred },
+		ProgressInterval: time.Nanosecond,
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, conn.WriteMessage(TextMessage, []byte("hello")))
+	_, data, err := conn.ReadMessage(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.Nil(t, conn.Close(CloseNormalClosure, ""))
+	assert.True(t, transferred > 0)
+}
+
+func TestWebSocketMetrics(t *testing.T) {
+	ts := echoWebSocket(t)
+	defer ts.Close()
+
+	s := NewSession()
+	defer s.Close()
+	s.Metrics = NewMetrics()
+	conn, err := s.WebSocket("ws"+strings.TrimPrefix(ts.URL, "http"), nil, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, conn.WriteMessage(TextMessage, []byte("hello")))
+	_, data, err := conn.ReadMessage(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.Nil(t, conn.Close(CloseNormalClosure, ""))
+
+	var b strings.Builder
+	s.Metrics.WriteTo(&b)
+	assert.Contains(t, b.String(), `status_class="1xx"} 1`)
+}
+
+func TestWebSocketServerClose(t *testing.T) {
+	ts := echoWebSocket(t)
+	defer ts.Close()
+
+	conn, err := WebSocket("ws"+strings.TrimPrefix(ts.URL, "http"), nil, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, conn.WriteMessage(TextMessage, []byte("bye")))
+	_, _, err = conn.ReadMessage(context.Background())
+	ce, ok := err.(*CloseError)
+	assert.True(t, ok)
+	assert.Equal(t, 1001, ce.Code)
+	assert.Equal(t, "bye", ce.Text)
+}
+
+func TestWebSocketReadContext(t *testing.T) {
+	ts := echoWebSocket(t)
+	defer ts.Close()
+
+	conn, err := WebSocket("ws"+strings.TrimPrefix(ts.URL, "http"), nil, nil)
+	assert.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _, err = conn.ReadMessage(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestWebSocketHandshakeError(t *testing.T) {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	_, err := WebSocket("ws"+strings.TrimPrefix(ts.URL, "http"), nil, nil)
+	assert.NotNil(t, err)
+}